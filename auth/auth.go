@@ -0,0 +1,81 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package auth contains authenticators for orasrv.GRPCServer (see orasrv.WithAuthenticator):
+// JWT verified with a local JWKS, mTLS client certificate subjects, and Basic credentials checked by an Oracle login.
+//
+// The authenticated Principal is put into the context, for the checkAuth, DBLog, BeforeHook... functions.
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// ErrNoCredentials is returned by an Authenticator if the request has no credentials of its kind.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Principal is the authenticated caller.
+type Principal struct {
+	// Name of the caller: the subject of the JWT, the common name of the certificate, or the database user.
+	Name string
+	// Roles of the caller, for orasrv.Authorizer.
+	Roles []string
+	// Method of the authentication: "jwt", "mtls" or "basic".
+	Method string
+	// Claims of the JWT.
+	Claims map[string]any
+}
+
+type principalCtxKey struct{}
+
+// NewContext returns a context carrying the principal.
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalCtxKey{}, p)
+}
+
+// FromContext returns the principal of the context.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalCtxKey{}).(Principal)
+	return p, ok
+}
+
+// Authenticator authenticates the caller of an incoming request.
+type Authenticator interface {
+	// Authenticate returns the principal of the caller,
+	// or ErrNoCredentials if the request has no credentials of its kind.
+	Authenticate(ctx context.Context) (Principal, error)
+}
+
+// AuthenticatorFunc is a function implementing Authenticator.
+type AuthenticatorFunc func(ctx context.Context) (Principal, error)
+
+func (f AuthenticatorFunc) Authenticate(ctx context.Context) (Principal, error) { return f(ctx) }
+
+// Chain returns an Authenticator trying the authenticators in order, till the first one which finds its credentials:
+// its result is returned, so wrong credentials are not tried with the next one.
+func Chain(authenticators ...Authenticator) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context) (Principal, error) {
+		for _, a := range authenticators {
+			p, err := a.Authenticate(ctx)
+			if !errors.Is(err, ErrNoCredentials) {
+				return p, err
+			}
+		}
+		return Principal{}, ErrNoCredentials
+	})
+}
+
+// authorization returns the credentials of the "authorization" metadata with the scheme (such as "Bearer").
+func authorization(ctx context.Context, scheme string) (string, bool) {
+	for _, v := range metadata.ValueFromIncomingContext(ctx, "authorization") {
+		if s, creds, ok := strings.Cut(v, " "); ok && strings.EqualFold(s, scheme) {
+			return strings.TrimSpace(creds), true
+		}
+	}
+	return "", false
+}
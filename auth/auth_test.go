@@ -0,0 +1,132 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestJWT(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: pub, KeyID: "k1", Algorithm: string(jose.EdDSA)}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := filepath.Join(t.TempDir(), "jwks.json")
+	if err = os.WriteFile(fn, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ReadJWKS(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := JWT{Keys: keys, Issuer: "https://idp", Audience: "oracall"}
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.EdDSA, Key: priv},
+		(&jose.SignerOptions{}).WithHeader(jose.HeaderKey("kid"), "k1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := func(claims jwt.Claims) context.Context {
+		raw, err := jwt.Signed(signer).Claims(claims).Claims(map[string]any{"roles": []string{"dba"}}).Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+raw))
+	}
+	exp := jwt.NewNumericDate(time.Now().Add(time.Hour))
+
+	p, err := a.Authenticate(token(jwt.Claims{Subject: "scott", Issuer: "https://idp", Audience: jwt.Audience{"oracall"}, Expiry: exp}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "scott" || p.Method != "jwt" || !slices.Equal(p.Roles, []string{"dba"}) {
+		t.Errorf("got %+v", p)
+	}
+
+	for name, claims := range map[string]jwt.Claims{
+		"issuer":    {Subject: "scott", Issuer: "https://evil", Audience: jwt.Audience{"oracall"}, Expiry: exp},
+		"audience":  {Subject: "scott", Issuer: "https://idp", Audience: jwt.Audience{"other"}, Expiry: exp},
+		"expired":   {Subject: "scott", Issuer: "https://idp", Audience: jwt.Audience{"oracall"}, Expiry: jwt.NewNumericDate(time.Now().Add(-time.Hour))},
+		"no expiry": {Subject: "scott", Issuer: "https://idp", Audience: jwt.Audience{"oracall"}},
+	} {
+		if _, err := a.Authenticate(token(claims)); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	if _, err := a.Authenticate(context.Background()); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("no token: got %+v", err)
+	}
+}
+
+func TestCert(t *testing.T) {
+	cert := x509.Certificate{Subject: pkix.Name{CommonName: "app", OrganizationalUnit: []string{"dba"}}}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{&cert}}},
+	}})
+	if p, err := (Cert{}).Authenticate(ctx); err != nil || p.Name != "app" || !slices.Equal(p.Roles, []string{"dba"}) {
+		t.Errorf("got %+v, %+v", p, err)
+	}
+	a := Cert{Subjects: map[string]Principal{"CN=app,OU=dba": {Name: "application", Roles: []string{"writer"}}}}
+	if p, err := a.Authenticate(ctx); err != nil || p.Name != "application" || p.Method != "mtls" {
+		t.Errorf("got %+v, %+v", p, err)
+	}
+	a.Subjects = map[string]Principal{}
+	if _, err := a.Authenticate(ctx); err == nil || errors.Is(err, ErrNoCredentials) {
+		t.Errorf("unknown subject: got %+v", err)
+	}
+}
+
+func TestBasicChain(t *testing.T) {
+	var calls int
+	basic := &Basic{Verify: func(_ context.Context, user, password string) (Principal, error) {
+		calls++
+		if user != "scott" || password != "tiger" {
+			return Principal{}, errors.New("invalid username/password")
+		}
+		return Principal{Name: "SCOTT", Roles: []string{"CONNECT"}}, nil
+	}}
+	a := Chain(&JWT{}, basic)
+	basicCtx := func(creds string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+			"authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(creds))))
+	}
+	for range 2 {
+		if p, err := a.Authenticate(basicCtx("scott:tiger")); err != nil || p.Name != "SCOTT" || p.Method != "basic" {
+			t.Errorf("got %+v, %+v", p, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("verified %d times", calls)
+	}
+	if _, err := a.Authenticate(basicCtx("scott:lion")); err == nil {
+		t.Error("wrong password accepted")
+	}
+	if _, err := a.Authenticate(context.Background()); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("no credentials: got %+v", err)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godror/godror"
+	"github.com/godror/godror/dsn"
+)
+
+// Basic authenticates the "authorization: Basic <base64(user:password)>" metadata with Verify,
+// caching the successful verifications for TTL.
+type Basic struct {
+	// Verify checks the credentials, such as OracleLogin.
+	Verify func(ctx context.Context, user, password string) (Principal, error)
+	// TTL is the time the successful verifications are cached for (5 minutes if zero, no caching if negative).
+	TTL time.Duration
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]cachedPrincipal
+}
+
+type cachedPrincipal struct {
+	Principal
+	until time.Time
+}
+
+func (a *Basic) Authenticate(ctx context.Context) (Principal, error) {
+	creds, ok := authorization(ctx, "Basic")
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+	b, err := base64.StdEncoding.DecodeString(creds)
+	if err != nil {
+		return Principal{}, errors.New("bad basic credentials")
+	}
+	user, password, ok := strings.Cut(string(b), ":")
+	if !ok || user == "" {
+		return Principal{}, errors.New("bad basic credentials")
+	}
+	ttl := a.TTL
+	if ttl == 0 {
+		ttl = 5 * time.Minute
+	}
+	key := sha256.Sum256(b)
+	now := time.Now()
+	if ttl > 0 {
+		a.mu.Lock()
+		cp, ok := a.cache[key]
+		a.mu.Unlock()
+		if ok && now.Before(cp.until) {
+			return cp.Principal, nil
+		}
+	}
+	p, err := a.Verify(ctx, user, password)
+	if err != nil {
+		return Principal{}, err
+	}
+	p.Method = "basic"
+	if ttl > 0 {
+		a.mu.Lock()
+		if a.cache == nil {
+			a.cache = make(map[[sha256.Size]byte]cachedPrincipal)
+		}
+		for k, cp := range a.cache {
+			if now.After(cp.until) {
+				delete(a.cache, k)
+			}
+		}
+		a.cache[key] = cachedPrincipal{Principal: p, until: now.Add(ttl)}
+		a.mu.Unlock()
+	}
+	return p, nil
+}
+
+// OracleLogin returns a Basic.Verify function, which logs in to the database of P as the user,
+// and returns its SESSION_ROLES as the roles of the principal.
+func OracleLogin(P dsn.ConnectionParams) func(ctx context.Context, user, password string) (Principal, error) {
+	return func(ctx context.Context, user, password string) (Principal, error) {
+		P := P
+		P.Username, P.Password = user, dsn.NewPassword(password)
+		P.StandaloneConnection = dsn.Bool(true)
+		db := sql.OpenDB(godror.NewConnector(P))
+		defer db.Close()
+		rows, err := db.QueryContext(ctx, "SELECT role FROM session_roles")
+		if err != nil {
+			var oerr *godror.OraErr
+			if errors.As(err, &oerr) && oerr.Code() == 1017 {
+				return Principal{}, errors.New("invalid username/password")
+			}
+			return Principal{}, err
+		}
+		defer rows.Close()
+		p := Principal{Name: strings.ToUpper(user)}
+		for rows.Next() {
+			var role string
+			if err := rows.Scan(&role); err != nil {
+				return p, err
+			}
+			p.Roles = append(p.Roles, role)
+		}
+		return p, rows.Err()
+	}
+}
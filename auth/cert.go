@@ -0,0 +1,42 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// Cert authenticates the callers by the subject of their verified TLS client certificate (mTLS).
+type Cert struct {
+	// Subjects maps the subjects of the certificates ("CN=app,OU=dba,O=Example") to the principals.
+	// If nil, the principal is named by the common name, with the organizational units as roles.
+	Subjects map[string]Principal
+}
+
+func (a Cert) Authenticate(ctx context.Context) (Principal, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+	ti, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(ti.State.VerifiedChains) == 0 || len(ti.State.VerifiedChains[0]) == 0 {
+		return Principal{}, ErrNoCredentials
+	}
+	cert := ti.State.VerifiedChains[0][0]
+	if a.Subjects == nil {
+		return Principal{Name: cert.Subject.CommonName, Roles: cert.Subject.OrganizationalUnit, Method: "mtls"}, nil
+	}
+	subject := cert.Subject.String()
+	pr, ok := a.Subjects[subject]
+	if !ok {
+		return Principal{}, fmt.Errorf("unknown certificate subject %q", subject)
+	}
+	pr.Method = "mtls"
+	return pr, nil
+}
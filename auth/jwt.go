@@ -0,0 +1,130 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// SignatureAlgorithms are the accepted signature algorithms of the JWTs.
+var SignatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512, jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512, jose.EdDSA,
+}
+
+// JWT authenticates the "authorization: Bearer <token>" metadata,
+// verifying the token with the keys of a JWKS, and checking its issuer, audience and expiry.
+type JWT struct {
+	Keys jose.JSONWebKeySet
+	// Issuer is the required "iss" claim, if not empty.
+	Issuer string
+	// Audience is the required "aud" claim, if not empty.
+	Audience string
+	// RolesClaim is the claim of the roles ("roles" if empty), a list or a space separated string.
+	RolesClaim string
+	// Leeway is the allowed clock skew (1 minute if zero).
+	Leeway time.Duration
+}
+
+// ReadJWKS reads the JSON Web Key Set file.
+func ReadJWKS(fileName string) (jose.JSONWebKeySet, error) {
+	var keys jose.JSONWebKeySet
+	fh, err := os.Open(fileName)
+	if err != nil {
+		return keys, err
+	}
+	defer fh.Close()
+	if err = json.NewDecoder(io.LimitReader(fh, 1<<20)).Decode(&keys); err != nil {
+		return keys, fmt.Errorf("%s: %w", fileName, err)
+	}
+	if len(keys.Keys) == 0 {
+		return keys, fmt.Errorf("%s: no keys", fileName)
+	}
+	return keys, nil
+}
+
+func (a *JWT) Authenticate(ctx context.Context) (Principal, error) {
+	raw, ok := authorization(ctx, "Bearer")
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+	tok, err := jwt.ParseSigned(raw, SignatureAlgorithms)
+	if err != nil {
+		return Principal{}, fmt.Errorf("parse JWT: %w", err)
+	}
+	key, err := a.key(tok)
+	if err != nil {
+		return Principal{}, err
+	}
+	var claims jwt.Claims
+	var all map[string]any
+	if err = tok.Claims(key, &claims, &all); err != nil {
+		return Principal{}, fmt.Errorf("verify JWT: %w", err)
+	}
+	if claims.Expiry == nil {
+		return Principal{}, errors.New("JWT without expiry")
+	}
+	want := jwt.Expected{Issuer: a.Issuer, Time: time.Now()}
+	if a.Audience != "" {
+		want.AnyAudience = jwt.Audience{a.Audience}
+	}
+	leeway := a.Leeway
+	if leeway == 0 {
+		leeway = jwt.DefaultLeeway
+	}
+	if err = claims.ValidateWithLeeway(want, leeway); err != nil {
+		return Principal{}, fmt.Errorf("validate JWT: %w", err)
+	}
+	rolesClaim := a.RolesClaim
+	if rolesClaim == "" {
+		rolesClaim = "roles"
+	}
+	return Principal{Name: claims.Subject, Roles: stringsOf(all[rolesClaim]), Method: "jwt", Claims: all}, nil
+}
+
+// key returns the key of the token's "kid" header - or the only key, if the token has no "kid".
+func (a *JWT) key(tok *jwt.JSONWebToken) (any, error) {
+	var kid string
+	if len(tok.Headers) != 0 {
+		kid = tok.Headers[0].KeyID
+	}
+	if kid == "" {
+		if len(a.Keys.Keys) == 1 {
+			return a.Keys.Keys[0], nil
+		}
+		return nil, errors.New("JWT without kid")
+	}
+	if keys := a.Keys.Key(kid); len(keys) != 0 {
+		return keys[0], nil
+	}
+	return nil, fmt.Errorf("unknown JWT kid %q", kid)
+}
+
+// stringsOf returns the strings of a claim: a list of strings, or a space separated string.
+func stringsOf(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		ss := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				ss = append(ss, s)
+			}
+		}
+		return ss
+	}
+	return nil
+}
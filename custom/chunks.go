@@ -0,0 +1,58 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package custom
+
+import (
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// Chunks reads a LOB in chunks of at most size bytes.
+type Chunks struct {
+	r      io.Reader
+	err    error
+	buf    []byte
+	n, cut int
+	isText bool
+}
+
+// NewChunks returns a new Chunks reading r.
+// For text (CLOB), the chunks are cut on UTF-8 character boundaries.
+func NewChunks(r io.Reader, size int, isText bool) *Chunks {
+	return &Chunks{r: r, buf: make([]byte, max(size, 2*utf8.UTFMax)), isText: isText}
+}
+
+// Next returns the next chunk, which is valid only till the next call.
+//
+// The last chunk is returned with io.EOF.
+func (c *Chunks) Next() ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	// keep the incomplete character of the previous chunk
+	c.n = copy(c.buf, c.buf[c.cut:c.n])
+	n, err := io.ReadFull(c.r, c.buf[c.n:])
+	c.n += n
+	c.cut = c.n
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = io.EOF
+		}
+		c.err = err
+		return c.buf[:c.n], err
+	}
+	if c.isText {
+		for i := c.n - 1; i >= 0 && i >= c.n-utf8.UTFMax; i-- {
+			if utf8.RuneStart(c.buf[i]) {
+				if !utf8.FullRune(c.buf[i:c.n]) {
+					c.cut = i
+				}
+				break
+			}
+		}
+	}
+	return c.buf[:c.cut], nil
+}
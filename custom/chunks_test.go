@@ -0,0 +1,41 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package custom_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/tgulacsi/oracall/custom"
+)
+
+func TestChunks(t *testing.T) {
+	src := strings.Repeat("árvíztűrő tükörfúrógép ", 100)
+	for _, size := range []int{8, 9, 10, 1 << 10, 1 << 20} {
+		chunks := custom.NewChunks(strings.NewReader(src), size, true)
+		var buf strings.Builder
+		for {
+			b, err := chunks.Next()
+			if len(b) > max(size, 8) {
+				t.Errorf("%d: got %d bytes", size, len(b))
+			}
+			if !utf8.Valid(b) {
+				t.Errorf("%d: chunk %q cuts a character", size, b)
+			}
+			buf.Write(b)
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := buf.String(); got != src {
+			t.Errorf("%d: got %q, wanted %q", size, got, src)
+		}
+	}
+}
@@ -66,6 +66,15 @@ func NumbersFromStrings(s *[]string) *[]godror.Number {
 	return (*[]godror.Number)(unsafe.Pointer(s))
 }
 
+// ResetMap empties the map *m, allocating it for n elements if it is nil.
+func ResetMap[V any](m *map[string]V, n int) {
+	if *m == nil {
+		*m = make(map[string]V, n)
+		return
+	}
+	clear(*m)
+}
+
 const timeFormat = time.RFC3339
 
 func ParseTime(t *time.Time, s string) error {
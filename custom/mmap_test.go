@@ -5,6 +5,7 @@
 package custom_test
 
 import (
+	"io"
 	"runtime"
 	"strings"
 	"testing"
@@ -37,3 +38,29 @@ func TestReadAll(t *testing.T) {
 	runtime.GC()
 	t.Log("GC didn't panic", b)
 }
+
+func TestSpill(t *testing.T) {
+	src := strings.Repeat("0123456789ABCDEF", 1024)
+	for _, threshold := range []int{0, 100, 1 << 20} {
+		spill := custom.NewSpill(threshold)
+		for i := 0; i < len(src); i += 1000 {
+			if _, err := io.WriteString(spill, src[i:min(len(src), i+1000)]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r, err := spill.Reader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(b); got != src {
+			t.Errorf("%d: got %q, wanted %q", threshold, got, src)
+		}
+		if err = spill.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+}
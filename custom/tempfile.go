@@ -46,3 +46,52 @@ func ReadAllString(r io.Reader, threshold int) (string, error) {
 	b, err := ReadAll(r, threshold)
 	return *((*string)(unsafe.Pointer(&b))), err
 }
+
+// Spill is an io.Writer which keeps the first threshold bytes in memory,
+// and spills the rest into a temp file - as ReadAll does.
+type Spill struct {
+	fh        *os.File
+	buf       bytes.Buffer
+	threshold int
+}
+
+// NewSpill returns a new Spill, keeping at most threshold bytes in memory.
+func NewSpill(threshold int) *Spill { return &Spill{threshold: threshold} }
+
+// Write the bytes into the memory buffer, or the temp file after the threshold.
+func (s *Spill) Write(p []byte) (int, error) {
+	if s.fh == nil {
+		if s.buf.Len()+len(p) <= s.threshold {
+			return s.buf.Write(p)
+		}
+		fh, err := os.CreateTemp("", "oracall-custom-spill-")
+		if err != nil {
+			return 0, err
+		}
+		os.Remove(fh.Name())
+		s.fh = fh
+	}
+	return s.fh.Write(p)
+}
+
+// Reader returns a reader of all the written bytes. Write must not be called after this.
+func (s *Spill) Reader() (io.Reader, error) {
+	if s.fh == nil {
+		return bytes.NewReader(s.buf.Bytes()), nil
+	}
+	if _, err := s.fh.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.MultiReader(bytes.NewReader(s.buf.Bytes()), s.fh), nil
+}
+
+// Close the temp file, if any.
+func (s *Spill) Close() error {
+	s.buf.Reset()
+	if s.fh == nil {
+		return nil
+	}
+	err := s.fh.Close()
+	s.fh = nil
+	return err
+}
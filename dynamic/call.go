@@ -0,0 +1,581 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dynamic
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/godror/godror"
+	"github.com/tgulacsi/oracall/custom"
+	oracall "github.com/tgulacsi/oracall/lib"
+	"github.com/tgulacsi/oracall/orasrv"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// batchSize is the number of cursor rows sent in one message.
+const batchSize = 1024
+
+// method is a callable function.
+type method struct {
+	desc  protoreflect.MethodDescriptor
+	fun   oracall.Function
+	qry   string
+	binds []oracall.Bind
+	// callArgs are the arguments of the function call in qry
+	callArgs []oracall.CallArg
+	// errors of the (oracall.orasrv.error) options
+	errCodes []orasrv.ErrorCode
+}
+
+func newMethod(fun oracall.Function) (*method, error) {
+	if len(fun.Results) != 0 {
+		return nil, fmt.Errorf("%s: implicit results: %w", fun.Name(), oracall.ErrUnsupported)
+	}
+	qry, binds, callArgs, err := fun.PlsqlCall()
+	if err != nil {
+		return nil, err
+	}
+	m := method{fun: fun, qry: qry, binds: binds, callArgs: callArgs}
+	for _, s := range fun.Errors {
+		ec, err := orasrv.ParseErrorCode(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fun.Name(), err)
+		}
+		m.errCodes = append(m.errCodes, ec)
+	}
+	return &m, nil
+}
+
+// call calls the function with the input, and sends the output (more than once for cursors and streamed LOBs).
+func (m *method) call(ctx context.Context, s *Server, input *dynamicpb.Message, send func(proto.Message) error) (err error) {
+	var lastDDL string
+	if !m.fun.LastDDL.IsZero() {
+		lastDDL = m.fun.LastDDL.UTC().Format(time.RFC3339)
+	}
+	ctx, call := oracall.StartCall(ctx, m.fun.Name(), lastDDL)
+	defer func() { call.End(err) }()
+	logger := zlog.SFromContext(ctx)
+	output := dynamicpb.NewMessage(m.desc.Output())
+	maxTableSize := oracall.MaxTableSize
+	for _, a := range m.fun.Args {
+		if fd := input.Descriptor().Fields().ByName(protoreflect.Name(a.Name)); fd != nil && fd.IsList() {
+			maxTableSize = max(maxTableSize, input.Get(fd).List().Len())
+		} else if fd != nil && fd.IsMap() {
+			maxTableSize = max(maxTableSize, input.Get(fd).Map().Len())
+		}
+	}
+
+	p := params{input: input, output: output, size: maxTableSize}
+	params := make([]any, len(m.binds), len(m.binds)+2)
+	first := make(map[string]int, len(m.binds))
+	for i, b := range m.binds {
+		if j, ok := first[b.Name]; ok {
+			// the same variable, as OUT
+			params[i] = params[j]
+			if o, ok := params[i].(sql.Out); ok {
+				o.In = false
+				params[i] = o
+			}
+			continue
+		}
+		first[b.Name] = i
+		var err error
+		if params[i], err = p.bind(b); err != nil {
+			return fmt.Errorf("%s: %w", b.Name, err)
+		}
+	}
+
+	qry := m.omitUnset(input)
+
+	if m.fun.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.fun.Timeout)
+		defer cancel()
+	}
+	txMode := m.fun.TxMode()
+	tx, endTx, err := s.Transactions.BeginTx(ctx, s.DB, txMode)
+	if err != nil {
+		return err
+	}
+	defer endTx()
+	defer tx.Rollback()
+	pkg, fn, _ := strings.Cut(m.fun.Name(), ".")
+	ctx = godror.ContextWithTraceTag(ctx, godror.TraceTag{Module: pkg, Action: fn})
+	readDbmsOutput, err := oracall.EnableDbmsOutput(ctx, tx, s.DbmsOutput)
+	if err != nil {
+		return err
+	}
+	logger.Info("calling", "fun", m.fun.Name())
+	endExec := call.Phase("exec")
+	_, err = tx.ExecContext(ctx, qry, append(params, godror.PlSQLArrays, godror.ArraySize(maxTableSize))...)
+	endExec(err)
+	if readDbmsOutput != nil {
+		if lines, outErr := readDbmsOutput(ctx); outErr != nil {
+			logger.Warn("dbms_output", "fun", m.fun.Name(), "error", outErr)
+		} else {
+			logger.Debug("dbms_output", "fun", m.fun.Name(), "lines", lines)
+		}
+	}
+	if err != nil {
+		return oracall.NewQueryError(qry, err)
+	}
+	for _, f := range p.fills {
+		if err := f(); err != nil {
+			return err
+		}
+	}
+
+	if len(p.streams) == 0 {
+		if err = send(output); err != nil {
+			return err
+		}
+		return endCall(call, tx, txMode)
+	}
+	for _, s := range p.streams {
+		defer s.Close()
+	}
+	if p.lobs != 0 {
+		// the first message carries the scalar outputs, the next ones the chunks
+		if err = send(output); err != nil {
+			return err
+		}
+		output = dynamicpb.NewMessage(m.desc.Output())
+	}
+	endIterate := call.Phase("iterate")
+	defer func() { endIterate(err) }()
+	for streams := p.streams; len(streams) != 0; {
+		next := streams[:0]
+		for _, s := range streams {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+			err := s.next(output, batchSize)
+			if fd := s.field(); fd.IsList() {
+				call.AddRows(output.Get(fd).List().Len())
+			}
+			if sendErr := send(output); sendErr != nil {
+				return sendErr
+			}
+			output.Clear(s.field())
+			if err == nil {
+				next = append(next, s)
+			} else if !errors.Is(err, io.EOF) {
+				return err
+			}
+		}
+		streams = next
+	}
+	endIterate(nil)
+	return endCall(call, tx, txMode)
+}
+
+// endCall commits the transaction, or rolls it back if the mode forbids committing.
+func endCall(call *oracall.Call, tx oracall.Tx, mode oracall.TxMode) error {
+	if !mode.Commits() {
+		return call.Rollback(tx)
+	}
+	return call.Commit(tx)
+}
+
+// omitUnset returns the query with the unset optional (defaulted) arguments left out of the call.
+func (m *method) omitUnset(input *dynamicpb.Message) string {
+	var omit []string
+	for _, a := range m.fun.Args {
+		if !a.IsOptional() {
+			continue
+		}
+		if fd := input.Descriptor().Fields().ByName(protoreflect.Name(a.Name)); fd != nil && fd.HasPresence() && !input.Has(fd) {
+			omit = append(omit, a.Name)
+		}
+	}
+	if len(omit) == 0 {
+		return m.qry
+	}
+	return oracall.OmitCallArgs(m.qry, m.fun.RealName(), m.callArgs, omit...)
+}
+
+// params builds the bind parameters from the input,
+// and collects the functions filling the output after the call.
+type params struct {
+	input, output *dynamicpb.Message
+	fills         []func() error
+	streams       []stream
+	lobs          int                 // the number of streamed LOBs
+	keys          map[string]*mapKeys // by argument name
+	size          int
+}
+
+func (p *params) bind(b oracall.Bind) (any, error) {
+	arg := b.Arg
+	name := protoreflect.Name(arg.Name)
+	var inFd, outFd protoreflect.FieldDescriptor
+	if arg.IsInput() {
+		if inFd = p.input.Descriptor().Fields().ByName(name); inFd == nil {
+			return nil, fmt.Errorf("no field %s in %s", name, p.input.Descriptor().FullName())
+		}
+	}
+	if arg.IsOutput() {
+		if outFd = p.output.Descriptor().Fields().ByName(name); outFd == nil {
+			return nil, fmt.Errorf("no field %s in %s", name, p.output.Descriptor().FullName())
+		}
+	}
+
+	switch {
+	case b.Key:
+		keys := p.keysOf(arg, inFd)
+		if outFd == nil {
+			return keys.in, nil
+		}
+		return sql.Out{Dest: keys.out, In: inFd != nil}, nil
+
+	case arg.Type == "REF CURSOR":
+		c := &cursor{fd: outFd, arg: arg}
+		dest := new(driver.Rows)
+		p.fills = append(p.fills, func() error {
+			if c.rows = *dest; c.rows != nil {
+				p.streams = append(p.streams, c)
+			}
+			return nil
+		})
+		return sql.Out{Dest: dest}, nil
+
+	case arg.IsStreamed():
+		lob := &godror.Lob{IsClob: arg.Type == "CLOB"}
+		if inFd != nil {
+			if lob.IsClob {
+				lob.Reader = strings.NewReader(p.input.Get(inFd).String())
+			} else {
+				lob.Reader = bytes.NewReader(p.input.Get(inFd).Bytes())
+			}
+		}
+		p.fills = append(p.fills, func() error {
+			if lob.Reader != nil {
+				p.streams = append(p.streams, &lobStream{fd: outFd, chunks: custom.NewChunks(lob.Reader, arg.ChunkSize, lob.IsClob)})
+				p.lobs++
+			}
+			return nil
+		})
+		return sql.Out{Dest: lob, In: inFd != nil}, nil
+
+	case arg.Flavor == oracall.FLAVOR_SIMPLE:
+		return p.bindSimple(*arg, inFd, outFd,
+			func() protoreflect.Message { return p.input },
+			func() protoreflect.Message { return p.output })
+
+	case arg.Flavor == oracall.FLAVOR_RECORD:
+		field := recordField(arg.RecordOf, b.Field)
+		if field == nil {
+			return nil, fmt.Errorf("no field %s in %s", b.Field, arg.Name)
+		}
+		fieldArg := *field
+		fieldArg.Direction = arg.Direction
+		var inSub, outSub protoreflect.FieldDescriptor
+		if inFd != nil {
+			if inSub = inFd.Message().Fields().ByName(protoreflect.Name(b.Field)); inSub == nil {
+				return nil, fmt.Errorf("no field %s in %s", b.Field, inFd.Message().FullName())
+			}
+		}
+		if outFd != nil {
+			if outSub = outFd.Message().Fields().ByName(protoreflect.Name(b.Field)); outSub == nil {
+				return nil, fmt.Errorf("no field %s in %s", b.Field, outFd.Message().FullName())
+			}
+		}
+		return p.bindSimple(fieldArg, inSub, outSub,
+			func() protoreflect.Message {
+				if !p.input.Has(inFd) {
+					return nil
+				}
+				return p.input.Get(inFd).Message()
+			},
+			func() protoreflect.Message { return p.output.Mutable(outFd).Message() })
+
+	case arg.TableOf != nil && arg.TableOf.Flavor == oracall.FLAVOR_SIMPLE:
+		var keys *mapKeys
+		if arg.IsMap() {
+			keys = p.keysOf(arg, inFd)
+		}
+		return p.bindTable(*arg.TableOf, inFd, outFd, nil, keys)
+
+	case arg.TableOf != nil && arg.TableOf.Flavor == oracall.FLAVOR_RECORD:
+		field := recordField(arg.TableOf.RecordOf, b.Field)
+		if field == nil {
+			return nil, fmt.Errorf("no field %s in %s", b.Field, arg.Name)
+		}
+		var keys *mapKeys
+		if arg.IsMap() {
+			keys = p.keysOf(arg, inFd)
+		}
+		return p.bindTable(*field, inFd, outFd, &b.Field, keys)
+	}
+	return nil, fmt.Errorf("%s: %w", arg.Name, oracall.ErrUnsupported)
+}
+
+// bindSimple binds a simple value of the message returned by in (and out).
+func (p *params) bindSimple(arg oracall.Argument, inFd, outFd protoreflect.FieldDescriptor, in, out func() protoreflect.Message) (any, error) {
+	fd := inFd
+	if fd == nil {
+		fd = outFd
+	}
+	k, err := kindOf(arg, fd)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if inFd != nil {
+		if m := in(); m != nil {
+			v = k.toOra(m.Get(inFd))
+		}
+	}
+	if outFd == nil {
+		return v, nil
+	}
+	dest := k.newDest(v)
+	p.fills = append(p.fills, func() error {
+		val, ok, err := k.fromOra(deref(dest), outFd)
+		if err != nil || !ok {
+			return err
+		}
+		out().Set(outFd, val)
+		return nil
+	})
+	return sql.Out{Dest: dest, In: inFd != nil}, nil
+}
+
+// mapKeys are the keys of a map (INDEX BY VARCHAR2) argument,
+// bound as an array parallel to the arrays of the values.
+type mapKeys struct {
+	in  []string // the sorted keys of the input
+	out *[]string
+}
+
+// keysOf returns the keys of the map argument, the same for all its binds.
+func (p *params) keysOf(arg *oracall.Argument, inFd protoreflect.FieldDescriptor) *mapKeys {
+	if keys := p.keys[arg.Name]; keys != nil {
+		return keys
+	}
+	keys := new(mapKeys)
+	if inFd != nil {
+		p.input.Get(inFd).Map().Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+			keys.in = append(keys.in, k.String())
+			return true
+		})
+		slices.Sort(keys.in)
+	}
+	out := append(make([]string, 0, max(p.size, len(keys.in))), keys.in...)
+	keys.out = &out
+	if p.keys == nil {
+		p.keys = make(map[string]*mapKeys)
+	}
+	p.keys[arg.Name] = keys
+	return keys
+}
+
+// bindTable binds an array of simple values (or of one field of the records, if field is not nil).
+// For maps, the values are in the order of the keys.
+func (p *params) bindTable(arg oracall.Argument, inFd, outFd protoreflect.FieldDescriptor, field *string, keys *mapKeys) (any, error) {
+	elemFd := func(fd protoreflect.FieldDescriptor) protoreflect.FieldDescriptor {
+		if fd == nil {
+			return nil
+		}
+		if fd.IsMap() {
+			fd = fd.MapValue()
+		}
+		if field == nil {
+			return fd
+		}
+		return fd.Message().Fields().ByName(protoreflect.Name(*field))
+	}
+	fd := elemFd(inFd)
+	if fd == nil {
+		fd = elemFd(outFd)
+	}
+	if fd == nil {
+		return nil, fmt.Errorf("no field %s in %s", *field, arg.Name)
+	}
+	k, err := kindOf(arg, fd)
+	if err != nil {
+		return nil, err
+	}
+	if k == kClob || k == kBlob {
+		return nil, fmt.Errorf("table of %s: %w", arg.Type, oracall.ErrUnsupported)
+	}
+	var vals []any
+	if inFd != nil {
+		var get func(int) protoreflect.Value
+		if keys != nil {
+			m := p.input.Get(inFd).Map()
+			vals = make([]any, len(keys.in))
+			get = func(i int) protoreflect.Value { return m.Get(protoreflect.ValueOfString(keys.in[i]).MapKey()) }
+		} else {
+			list := p.input.Get(inFd).List()
+			vals = make([]any, list.Len())
+			get = list.Get
+		}
+		for i := range vals {
+			v := get(i)
+			if field != nil {
+				m := v.Message()
+				if !m.Has(fd) {
+					continue
+				}
+				v = m.Get(fd)
+			}
+			vals[i] = k.toOra(v)
+		}
+	}
+	slice := k.newSlice(vals, p.size)
+	if outFd == nil {
+		return deref(slice), nil
+	}
+	if keys != nil {
+		p.fills = append(p.fills, func() error {
+			m := p.output.Mutable(outFd).Map()
+			for i, v := range elems(slice) {
+				if i >= len(*keys.out) {
+					break
+				}
+				key := protoreflect.ValueOfString((*keys.out)[i]).MapKey()
+				val, ok, err := k.fromOra(v, fd)
+				if err != nil {
+					return err
+				}
+				if field != nil {
+					if rec := m.Mutable(key).Message(); ok {
+						rec.Set(fd, val)
+					}
+				} else if ok {
+					m.Set(key, val)
+				} else {
+					m.Set(key, m.NewValue())
+				}
+			}
+			return nil
+		})
+		return sql.Out{Dest: slice, In: inFd != nil}, nil
+	}
+	p.fills = append(p.fills, func() error {
+		list := p.output.Mutable(outFd).List()
+		for i, v := range elems(slice) {
+			val, ok, err := k.fromOra(v, fd)
+			if err != nil {
+				return err
+			}
+			if field == nil {
+				if ok {
+					list.Append(val)
+				} else {
+					list.Append(list.NewElement())
+				}
+				continue
+			}
+			for list.Len() <= i {
+				list.Append(list.NewElement())
+			}
+			if ok {
+				list.Get(i).Message().Set(fd, val)
+			}
+		}
+		return nil
+	})
+	return sql.Out{Dest: slice, In: inFd != nil}, nil
+}
+
+func recordField(fields []oracall.NamedArgument, name string) *oracall.Argument {
+	for _, f := range fields {
+		if f.Name == name {
+			return f.Argument
+		}
+	}
+	return nil
+}
+
+// cursor is a REF CURSOR output, sent in batches.
+// stream is an output sent in more messages: a cursor or a streamed LOB.
+type stream interface {
+	// next reads the next batch into the output message.
+	next(output *dynamicpb.Message, n int) error
+	field() protoreflect.FieldDescriptor
+	Close() error
+}
+
+type cursor struct {
+	rows driver.Rows
+	fd   protoreflect.FieldDescriptor
+	arg  *oracall.Argument
+}
+
+func (c *cursor) field() protoreflect.FieldDescriptor { return c.fd }
+func (c *cursor) Close() error                        { return c.rows.Close() }
+
+// next reads at most n rows into the output message.
+func (c *cursor) next(output *dynamicpb.Message, n int) error {
+	list := output.Mutable(c.fd).List()
+	md := c.fd.Message()
+	cols := c.arg.TableOf.RecordOf
+	vals := make([]driver.Value, len(c.rows.Columns()))
+	for range n {
+		if err := c.rows.Next(vals); err != nil {
+			return err
+		}
+		row := list.NewElement()
+		for i, col := range cols {
+			if i >= len(vals) {
+				break
+			}
+			fd := md.Fields().ByName(protoreflect.Name(col.Name))
+			if fd == nil {
+				continue
+			}
+			k, err := kindOf(*col.Argument, fd)
+			if err != nil {
+				return err
+			}
+			val, ok, err := k.fromOra(vals[i], fd)
+			if err != nil {
+				return fmt.Errorf("%s: %w", col.Name, err)
+			}
+			if ok {
+				row.Message().Set(fd, val)
+			}
+		}
+		list.Append(row)
+	}
+	return nil
+}
+
+// lobStream sends the LOB in chunks.
+type lobStream struct {
+	fd     protoreflect.FieldDescriptor
+	chunks *custom.Chunks
+}
+
+func (l *lobStream) field() protoreflect.FieldDescriptor { return l.fd }
+func (l *lobStream) Close() error                        { return nil }
+
+// next reads the next chunk into the output message.
+func (l *lobStream) next(output *dynamicpb.Message, _ int) error {
+	b, err := l.chunks.Next()
+	if len(b) != 0 {
+		if l.fd.Kind() == protoreflect.StringKind {
+			output.Set(l.fd, protoreflect.ValueOfString(string(b)))
+		} else {
+			output.Set(l.fd, protoreflect.ValueOfBytes(bytes.Clone(b)))
+		}
+	}
+	return err
+}
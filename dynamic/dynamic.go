@@ -0,0 +1,386 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package dynamic serves the PL/SQL functions of a package cache directory
+// over gRPC, without generated code: the protobuf descriptors are built
+// and the PL/SQL blocks are prepared at runtime.
+package dynamic
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/bufbuild/protocompile"
+	oracall "github.com/tgulacsi/oracall/lib"
+	"github.com/tgulacsi/oracall/orasrv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionpbAlpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// tagProto is the source of orasrv/tag.proto, imported by the generated .proto for the tags.
+const tagProto = `syntax = "proto3";
+package oracall.orasrv;
+
+import "google/protobuf/descriptor.proto";
+
+option go_package = "github.com/tgulacsi/oracall/orasrv;orasrv";
+
+extend google.protobuf.MethodOptions {
+  repeated string tag = 13020;
+  // timeout of the call on the server side, as a Go duration (30s).
+  string timeout = 13021;
+  // maps ORA error codes to gRPC codes: "1403=NOT_FOUND", "20001..20099=INVALID_ARGUMENT".
+  repeated string error = 13022;
+  // transaction mode: comma separated readonly, serializable, nocommit.
+  string tx = 13024;
+}
+
+extend google.protobuf.FieldOptions {
+  // the value of the field is redacted in the logs (--oracall:secret).
+  bool secret = 13023;
+}
+`
+
+// Server serves the functions of the package caches in Dir, calling them through DB.
+//
+// Register it on a grpc.Server created with its ServerOption.
+type Server struct {
+	DB  *sql.DB
+	Dir string
+	// Filter filters the functions ("PKG.FUNC"), as in oracall.ParsePackageCaches.
+	Filter func(string) bool
+	// RetryPolicy retries the idempotent functions on transient errors.
+	RetryPolicy oracall.RetryPolicy
+	// Transactions are begun by the <Pkg>Tx services (with oracall.TxService),
+	// and joined by the calls with their handle in the metadata.
+	Transactions *oracall.Transactions
+	// DbmsOutput captures the DBMS_OUTPUT of every call, not just of those asking for it in their metadata.
+	DbmsOutput bool
+
+	mu       sync.RWMutex
+	state    *state
+	lastDDLs map[string]time.Time
+}
+
+// state is everything built from one version of the package caches.
+type state struct {
+	files    *protoregistry.Files
+	methods  map[string]*method // by full method name: /pkg.Service/Method
+	services map[string]grpc.ServiceInfo
+	// txMethods are the methods of the <Pkg>Tx services
+	txMethods map[string]protoreflect.MethodDescriptor
+}
+
+// New returns a Server serving the functions of the package caches in dir.
+func New(ctx context.Context, db *sql.DB, dir string, filter func(string) bool) (*Server, error) {
+	s := &Server{DB: db, Dir: dir, Filter: filter, Transactions: new(oracall.Transactions)}
+	if _, err := s.Load(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ServerOption returns the grpc.ServerOption that routes all calls of unknown services to s.
+func (s *Server) ServerOption() grpc.ServerOption {
+	return grpc.UnknownServiceHandler(s.handle)
+}
+
+// Register registers the gRPC reflection service on gs,
+// listing the services of gs and the dynamic services of s.
+func (s *Server) Register(gs *grpc.Server) {
+	opts := reflection.ServerOptions{
+		Services:           serviceInfos{gs, s},
+		DescriptorResolver: resolver{s},
+	}
+	rs := reflection.NewServerV1(opts)
+	reflectionpb.RegisterServerReflectionServer(gs, rs)
+	reflectionpbAlpha.RegisterServerReflectionServer(gs, reflection.NewServer(opts))
+}
+
+// GetServiceInfo returns the dynamic services.
+func (s *Server) GetServiceInfo() map[string]grpc.ServiceInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.state == nil {
+		return nil
+	}
+	return s.state.services
+}
+
+// Watch reloads the package caches in every interval, till ctx is done.
+// refresh, if not nil, is called before each reload (to update the caches from the DB, for example).
+func (s *Server) Watch(ctx context.Context, interval time.Duration, refresh func(context.Context) error) error {
+	logger := zlog.SFromContext(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if refresh != nil {
+			if err := refresh(ctx); err != nil {
+				logger.Error("refresh", "error", err)
+				continue
+			}
+		}
+		if changed, err := s.Load(ctx); err != nil {
+			logger.Error("reload", "dir", s.Dir, "error", err)
+		} else if changed {
+			logger.Info("reloaded", "dir", s.Dir)
+		}
+	}
+}
+
+// Load (re)loads the package caches, if any of their LastDDL has changed since the last load.
+//
+// The calls already running finish with the old definitions.
+func (s *Server) Load(ctx context.Context) (bool, error) {
+	logger := zlog.SFromContext(ctx)
+	names, err := oracall.ListPackageCaches(s.Dir)
+	if err != nil {
+		return false, err
+	}
+	pcs := make([]oracall.PackageCache, 0, len(names))
+	lastDDLs := make(map[string]time.Time, len(names))
+	for _, nm := range names {
+		if s.Filter != nil && !s.Filter(nm+".") {
+			continue
+		}
+		pc, err := oracall.ReadPackageCache(ctx, s.Dir, nm)
+		if err != nil {
+			return false, err
+		}
+		pcs = append(pcs, pc)
+		lastDDLs[pc.Name] = pc.LastDDL
+	}
+	s.mu.RLock()
+	unchanged := s.state != nil && maps.EqualFunc(s.lastDDLs, lastDDLs, time.Time.Equal)
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	st, err := build(ctx, pcs, s.Filter)
+	if err != nil {
+		return false, err
+	}
+	logger.Info("loaded", "packages", slices.Sorted(maps.Keys(lastDDLs)), "methods", len(st.methods))
+	s.mu.Lock()
+	s.state, s.lastDDLs = st, lastDDLs
+	s.mu.Unlock()
+	return true, nil
+}
+
+// build compiles the .proto of each package, and prepares the PL/SQL blocks.
+func build(ctx context.Context, pcs []oracall.PackageCache, filter func(string) bool) (*state, error) {
+	logger := zlog.SFromContext(ctx)
+	st := state{
+		files:     new(protoregistry.Files),
+		methods:   make(map[string]*method),
+		services:  make(map[string]grpc.ServiceInfo),
+		txMethods: make(map[string]protoreflect.MethodDescriptor),
+	}
+	sources := map[string]string{"github.com/tgulacsi/oracall/orasrv/tag.proto": tagProto}
+	methods := make(map[string]map[string]*method, len(pcs))
+	txServices := make(map[string]protoreflect.Name, len(pcs))
+	for _, pc := range pcs {
+		functions := oracall.ApplyAnnotations(oracall.ParsePackageCache(ctx, pc, filter), pc.Annotations)
+		pkg := strings.ToLower(pc.Name)
+		ms := make(map[string]*method, len(functions))
+		supported := functions[:0]
+		for _, f := range functions {
+			m, err := newMethod(f)
+			if err != nil {
+				logger.Warn("skip", "function", f.Name(), "error", err)
+				continue
+			}
+			ms[f.RPCName()] = m
+			supported = append(supported, f)
+		}
+		if len(supported) == 0 {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := saveProtobuf(ctx, &buf, supported, pkg); err != nil {
+			return nil, fmt.Errorf("%s: %w", pc.Name, err)
+		}
+		sources[pkg+".proto"] = buf.String()
+		methods[pkg+".proto"] = ms
+		txServices[pkg+".proto"] = protoreflect.Name(oracall.CamelCase(pkg) + "Tx")
+	}
+
+	comp := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+	}
+	fns := slices.Sorted(maps.Keys(methods))
+	files, err := comp.Compile(ctx, fns...)
+	if err != nil {
+		return nil, err
+	}
+	for _, fd := range files {
+		if err := register(st.files, fd); err != nil {
+			return nil, err
+		}
+		svcs := fd.Services()
+		for i := range svcs.Len() {
+			sd := svcs.Get(i)
+			info := grpc.ServiceInfo{Metadata: fd.Path()}
+			mds := sd.Methods()
+			for j := range mds.Len() {
+				md := mds.Get(j)
+				fullMethod := "/" + string(sd.FullName()) + "/" + string(md.Name())
+				if sd.Name() == txServices[fd.Path()] {
+					st.txMethods[fullMethod] = md
+				} else if m := methods[fd.Path()][string(md.Name())]; m != nil {
+					m.desc = md
+					st.methods[fullMethod] = m
+				} else {
+					continue
+				}
+				info.Methods = append(info.Methods, grpc.MethodInfo{
+					Name: string(md.Name()), IsServerStream: md.IsStreamingServer(),
+				})
+			}
+			st.services[string(sd.FullName())] = info
+		}
+	}
+	return &st, nil
+}
+
+// saveProtobuf writes the .proto of the package, catching the panics of oracall.SaveProtobuf.
+func saveProtobuf(ctx context.Context, w io.Writer, functions []oracall.Function, pkg string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v: %w", r, oracall.ErrUnsupported)
+		}
+	}()
+	return oracall.SaveProtobuf(ctx, w, functions, pkg, "")
+}
+
+// register registers fd and its imports in files.
+func register(files *protoregistry.Files, fd protoreflect.FileDescriptor) error {
+	if _, err := files.FindFileByPath(fd.Path()); err == nil {
+		return nil
+	}
+	imports := fd.Imports()
+	for i := range imports.Len() {
+		if err := register(files, imports.Get(i).FileDescriptor); err != nil {
+			return err
+		}
+	}
+	return files.RegisterFile(fd)
+}
+
+// MethodTags returns the tags of the method (/pkg.Service/Method), for orasrv.Authorizer.
+func (s *Server) MethodTags(fullMethod string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.state != nil {
+		if m := s.state.methods[fullMethod]; m != nil {
+			return m.fun.Tag
+		}
+	}
+	return nil
+}
+
+// handle is the grpc.StreamHandler of all the dynamic methods.
+func (s *Server) handle(_ any, stream grpc.ServerStream) error {
+	fullMethod, _ := grpc.MethodFromServerStream(stream)
+	s.mu.RLock()
+	var m *method
+	var txMethod protoreflect.MethodDescriptor
+	if s.state != nil {
+		m, txMethod = s.state.methods[fullMethod], s.state.txMethods[fullMethod]
+	}
+	s.mu.RUnlock()
+	if txMethod != nil {
+		return orasrv.StatusErrorWith(s.handleTx(stream, txMethod), nil)
+	}
+	if m == nil {
+		return status.Errorf(codes.Unimplemented, "unknown method %s", fullMethod)
+	}
+	input := dynamicpb.NewMessage(m.desc.Input())
+	if err := stream.RecvMsg(input); err != nil {
+		return err
+	}
+	call := func(ctx context.Context) error {
+		return m.call(ctx, s, input, func(output proto.Message) error {
+			return stream.SendMsg(output)
+		})
+	}
+	var err error
+	if m.fun.Retryable() && !oracall.JoinsTx(stream.Context()) {
+		err = s.RetryPolicy.Do(stream.Context(), m.fun.MaxAttempts, call)
+	} else {
+		err = call(stream.Context())
+	}
+	return orasrv.StatusErrorWith(err, m.errCodes)
+}
+
+// serviceInfos merges the static and the dynamic services, for reflection.
+type serviceInfos []reflection.ServiceInfoProvider
+
+func (ss serviceInfos) GetServiceInfo() map[string]grpc.ServiceInfo {
+	m := make(map[string]grpc.ServiceInfo)
+	for _, s := range ss {
+		maps.Copy(m, s.GetServiceInfo())
+	}
+	return m
+}
+
+// Resolver returns the resolver of the dynamic descriptors (then the global ones),
+// for orasrv.WithResolver, orasrv.Authorizer and orasrv.Metrics.
+func (s *Server) Resolver() protodesc.Resolver { return resolver{s} }
+
+// resolver resolves the dynamic descriptors first, then the global ones.
+type resolver struct{ s *Server }
+
+func (r resolver) files() *protoregistry.Files {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	if r.s.state == nil {
+		return new(protoregistry.Files)
+	}
+	return r.s.state.files
+}
+
+func (r resolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	fd, err := r.files().FindFileByPath(path)
+	if errors.Is(err, protoregistry.NotFound) {
+		return protoregistry.GlobalFiles.FindFileByPath(path)
+	}
+	return fd, err
+}
+
+func (r resolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	d, err := r.files().FindDescriptorByName(name)
+	if errors.Is(err, protoregistry.NotFound) {
+		return protoregistry.GlobalFiles.FindDescriptorByName(name)
+	}
+	return d, err
+}
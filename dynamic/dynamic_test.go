@@ -0,0 +1,353 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dynamic
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/godror/godror"
+	oracall "github.com/tgulacsi/oracall/lib"
+	"github.com/tgulacsi/oracall/orasrv"
+	"github.com/tgulacsi/oracall/source"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const testSpec = `CREATE OR REPLACE PACKAGE scott.pkg AS
+  TYPE num_tab IS TABLE OF NUMBER INDEX BY PLS_INTEGER;
+  TYPE rec IS RECORD (a NUMBER(9), b VARCHAR2(10), c DATE);
+  TYPE rec_tab IS TABLE OF rec INDEX BY PLS_INTEGER;
+  TYPE rec_cur IS REF CURSOR RETURN rec;
+  TYPE num_map IS TABLE OF NUMBER INDEX BY VARCHAR2(30);
+  TYPE rec_map IS TABLE OF rec INDEX BY VARCHAR2(30);
+  --oracall:error NO_DATA_FOUND => NOT_FOUND
+
+  FUNCTION sum_it(p_nums IN num_tab, p_opt IN OUT VARCHAR2) RETURN NUMBER;
+  PROCEDURE recs(p_rec IN rec, p_recs OUT rec_tab);
+  PROCEDURE cur(p_id IN PLS_INTEGER, p_cur OUT rec_cur);
+  PROCEDURE maps(p_nums IN num_map, p_recs OUT rec_map);
+  --oracall:secret defs.p_name
+  PROCEDURE defs(p_id IN PLS_INTEGER, p_name IN VARCHAR2 DEFAULT 'x', p_day IN DATE := SYSDATE);
+  --oracall:stream doc.p_doc=16
+  PROCEDURE doc(p_id IN PLS_INTEGER, p_title OUT VARCHAR2, p_doc OUT CLOB);
+  --oracall:upload put_doc.p_doc
+  --oracall:tag put_doc => writer
+  PROCEDURE put_doc(p_id IN PLS_INTEGER, p_doc IN BLOB);
+END pkg;
+`
+
+func newTestServer(t *testing.T, lastDDL time.Time) *Server {
+	t.Helper()
+	ctx := zlog.NewSContext(context.Background(), zlog.NewT(t).SLog())
+	pcs, err := source.ParseSpec(ctx, testSpec, "", lastDDL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, pc := range pcs {
+		if err := oracall.WritePackageCache(ctx, dir, pc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := New(ctx, nil, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestLoad(t *testing.T) {
+	oracall.TxService = true
+	t.Cleanup(func() { oracall.TxService = false })
+	s := newTestServer(t, time.Unix(1, 0))
+	infos := s.GetServiceInfo()
+	info, ok := infos["pkg.Pkg"]
+	if !ok {
+		t.Fatalf("no pkg.Pkg in %v", infos)
+	}
+	streams := make(map[string]bool)
+	for _, m := range info.Methods {
+		streams[m.Name] = m.IsServerStream
+	}
+	if len(streams) != 7 || !streams["Cur"] || !streams["Doc"] || streams["SumIt"] {
+		t.Errorf("got %v", streams)
+	}
+	for _, nm := range []string{"/pkg.Pkg/SumIt", "/pkg.Pkg/Recs", "/pkg.Pkg/Cur", "/pkg.Pkg/Maps", "/pkg.Pkg/Defs", "/pkg.Pkg/Doc", "/pkg.Pkg/PutDoc"} {
+		if s.state.methods[nm] == nil {
+			t.Errorf("no method %s", nm)
+		}
+	}
+	if ecs := s.state.methods["/pkg.Pkg/Cur"].errCodes; len(ecs) != 1 ||
+		ecs[0] != (orasrv.ErrorCode{From: 1403, To: 1403, Code: codes.NotFound}) {
+		t.Errorf("error codes: got %v", ecs)
+	}
+	if tags := s.MethodTags("/pkg.Pkg/PutDoc"); !slices.Equal(tags, []string{"writer"}) {
+		t.Errorf("tags: got %q", tags)
+	}
+	if d, err := s.Resolver().FindDescriptorByName("pkg.Pkg.Cur"); err != nil {
+		t.Error(err)
+	} else if ecs := orasrv.MethodErrorCodes(d.(protoreflect.MethodDescriptor)); len(ecs) != 1 || ecs[0].Code != codes.NotFound {
+		t.Errorf("resolved error codes: got %v", ecs)
+	}
+	authz := orasrv.Authorizer{Policy: orasrv.Policy{Tags: map[string][]string{"writer": {"admin"}}}, Resolver: s.Resolver()}
+	if err := authz.CheckAuth(context.Background(), "/pkg.Pkg/PutDoc"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("resolved tags: got %v", err)
+	}
+	if err := authz.CheckAuth(context.Background(), "/pkg.Pkg/Cur"); err != nil {
+		t.Errorf("untagged: got %v", err)
+	}
+	defs := dynamicpb.NewMessage(s.state.methods["/pkg.Pkg/Defs"].desc.Input())
+	pName := defs.Descriptor().Fields().ByName("p_name")
+	defs.Set(pName, protoreflect.ValueOfString("tiger"))
+	if got := oracall.Redact(defs).ProtoReflect().Get(pName).String(); got != oracall.Redacted {
+		t.Errorf("secret: got %q", got)
+	}
+	for _, nm := range []string{"/pkg.PkgTx/Begin", "/pkg.PkgTx/Commit", "/pkg.PkgTx/Rollback"} {
+		if s.state.txMethods[nm] == nil {
+			t.Errorf("no transaction method %s", nm)
+		}
+	}
+	if _, err := (resolver{s}).FindDescriptorByName("pkg.Pkg"); err != nil {
+		t.Error(err)
+	}
+	if _, err := (resolver{s}).FindFileByPath("google/protobuf/timestamp.proto"); err != nil {
+		t.Error(err)
+	}
+
+	ctx := zlog.NewSContext(context.Background(), zlog.NewT(t).SLog())
+	if changed, err := s.Load(ctx); err != nil || changed {
+		t.Errorf("reload without change: %t %+v", changed, err)
+	}
+	pcs, err := source.ParseSpec(ctx, testSpec, "", time.Unix(2, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := oracall.WritePackageCache(ctx, s.Dir, pcs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := s.Load(ctx); err != nil || !changed {
+		t.Errorf("reload after change: %t %+v", changed, err)
+	}
+}
+
+func TestBind(t *testing.T) {
+	s := newTestServer(t, time.Time{})
+	bind := func(t *testing.T, name string, set func(*dynamicpb.Message)) (*method, *params, []any) {
+		t.Helper()
+		m := s.state.methods["/pkg.Pkg/"+name]
+		input := dynamicpb.NewMessage(m.desc.Input())
+		set(input)
+		p := &params{input: input, output: dynamicpb.NewMessage(m.desc.Output()), size: 4}
+		vals := make([]any, len(m.binds))
+		for i, b := range m.binds {
+			var err error
+			if vals[i], err = p.bind(b); err != nil {
+				t.Fatalf("%d. %s: %+v", i, b.Name, err)
+			}
+		}
+		return m, p, vals
+	}
+	field := func(m protoreflect.Message, name string) protoreflect.FieldDescriptor {
+		return m.Descriptor().Fields().ByName(protoreflect.Name(name))
+	}
+
+	t.Run("SumIt", func(t *testing.T) {
+		m, p, vals := bind(t, "SumIt", func(input *dynamicpb.Message) {
+			l := input.Mutable(field(input, "p_nums")).List()
+			l.Append(protoreflect.ValueOfString("1"))
+			l.Append(protoreflect.ValueOfString("2.5"))
+			input.Set(field(input, "p_opt"), protoreflect.ValueOfString("x"))
+		})
+		for i, b := range m.binds {
+			switch b.Arg.Name {
+			case "p_nums":
+				if nums, ok := vals[i].([]godror.Number); !ok || len(nums) != 2 || nums[1] != "2.5" {
+					t.Errorf("p_nums: got %#v", vals[i])
+				}
+			case "p_opt":
+				o := vals[i].(sql.Out)
+				if !o.In || *(o.Dest.(*string)) != "x" {
+					t.Errorf("p_opt: got %#v", o)
+				}
+				*(o.Dest.(*string)) = "y"
+			case "ret":
+				*(vals[i].(sql.Out).Dest.(*godror.Number)) = "3.5"
+			}
+		}
+		for _, f := range p.fills {
+			if err := f(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := p.output.Get(field(p.output, "p_opt")).String(); got != "y" {
+			t.Errorf("p_opt: got %q", got)
+		}
+		if got := p.output.Get(field(p.output, "ret")).String(); got != "3.5" {
+			t.Errorf("ret: got %q", got)
+		}
+	})
+
+	t.Run("Recs", func(t *testing.T) {
+		now := time.Unix(time.Now().Unix(), 0)
+		m, p, vals := bind(t, "Recs", func(input *dynamicpb.Message) {
+			rec := input.Mutable(field(input, "p_rec")).Message()
+			rec.Set(field(rec, "a"), protoreflect.ValueOfInt32(9))
+			rec.Set(field(rec, "b"), protoreflect.ValueOfString("b"))
+			ts := rec.Mutable(field(rec, "c")).Message()
+			ts.Set(field(ts, "seconds"), protoreflect.ValueOfInt64(now.Unix()))
+		})
+		var n int
+		for i, b := range m.binds {
+			if b.Arg.Name == "p_rec" {
+				switch b.Field {
+				case "a":
+					if vals[i] != int32(9) {
+						t.Errorf("a: got %#v", vals[i])
+					}
+				case "b":
+					if vals[i] != "b" {
+						t.Errorf("b: got %#v", vals[i])
+					}
+				case "c":
+					if got, ok := vals[i].(time.Time); !ok || !got.Equal(now) {
+						t.Errorf("c: got %#v", vals[i])
+					}
+				}
+				continue
+			}
+			// p_recs, an array for each field
+			n++
+			switch dest := vals[i].(sql.Out).Dest.(type) {
+			case *[]int32:
+				*dest = append(*dest, 1, 2)
+			case *[]string:
+				*dest = append(*dest, "a", "")
+			case *[]time.Time:
+				*dest = append(*dest, now, time.Time{})
+			default:
+				t.Errorf("%s: got %T", b.Field, dest)
+			}
+		}
+		if n != 3 {
+			t.Errorf("got %d binds for p_recs, wanted 3", n)
+		}
+		for _, f := range p.fills {
+			if err := f(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		l := p.output.Get(field(p.output, "p_recs")).List()
+		if l.Len() != 2 {
+			t.Fatalf("got %d records", l.Len())
+		}
+		rec := l.Get(1).Message()
+		if a, b := rec.Get(field(rec, "a")).Int(), rec.Get(field(rec, "b")).String(); a != 2 || b != "" || rec.Has(field(rec, "c")) {
+			t.Errorf("got a=%d b=%q c=%v", a, b, rec.Get(field(rec, "c")))
+		}
+	})
+	t.Run("Maps", func(t *testing.T) {
+		m, p, vals := bind(t, "Maps", func(input *dynamicpb.Message) {
+			nums := input.Mutable(field(input, "p_nums")).Map()
+			nums.Set(protoreflect.ValueOfString("b").MapKey(), protoreflect.ValueOfString("2"))
+			nums.Set(protoreflect.ValueOfString("a").MapKey(), protoreflect.ValueOfString("1"))
+		})
+		for i, b := range m.binds {
+			switch {
+			case b.Arg.Name == "p_nums" && b.Key:
+				if keys, ok := vals[i].([]string); !ok || len(keys) != 2 || keys[0] != "a" {
+					t.Errorf("p_nums keys: got %#v", vals[i])
+				}
+			case b.Arg.Name == "p_nums":
+				if nums, ok := vals[i].([]godror.Number); !ok || len(nums) != 2 || nums[0] != "1" {
+					t.Errorf("p_nums: got %#v", vals[i])
+				}
+			case b.Key:
+				*(vals[i].(sql.Out).Dest.(*[]string)) = []string{"x", "y"}
+			case b.Field == "a":
+				*(vals[i].(sql.Out).Dest.(*[]int32)) = []int32{5, 6}
+			}
+		}
+		for _, f := range p.fills {
+			if err := f(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		recs := p.output.Get(field(p.output, "p_recs")).Map()
+		if recs.Len() != 2 {
+			t.Fatalf("got %d records", recs.Len())
+		}
+		rec := recs.Get(protoreflect.ValueOfString("y").MapKey()).Message()
+		if a := rec.Get(field(rec, "a")).Int(); a != 6 {
+			t.Errorf("got a=%d", a)
+		}
+	})
+	t.Run("Defs", func(t *testing.T) {
+		m, _, _ := bind(t, "Defs", func(input *dynamicpb.Message) {
+			input.Set(field(input, "p_id"), protoreflect.ValueOfInt32(1))
+		})
+		input := dynamicpb.NewMessage(m.desc.Input())
+		if fd := field(input, "p_name"); !fd.HasPresence() {
+			t.Errorf("p_name is not optional")
+		}
+		if qry := m.omitUnset(input); strings.Contains(qry, "p_name=>") || strings.Contains(qry, "p_day=>") || !strings.Contains(qry, "p_id=>") {
+			t.Errorf("unset defaults are not omitted: %s", qry)
+		}
+		input.Set(field(input, "p_name"), protoreflect.ValueOfString(""))
+		if qry := m.omitUnset(input); !strings.Contains(qry, "p_name=>") || strings.Contains(qry, "p_day=>") {
+			t.Errorf("set default is omitted: %s", qry)
+		}
+	})
+
+	t.Run("Doc", func(t *testing.T) {
+		m, p, vals := bind(t, "Doc", func(input *dynamicpb.Message) {
+			input.Set(field(input, "p_id"), protoreflect.ValueOfInt32(1))
+		})
+		doc := strings.Repeat("0123456789", 5)
+		for i, b := range m.binds {
+			if b.Arg.Name != "p_doc" {
+				continue
+			}
+			out, ok := vals[i].(sql.Out)
+			if !ok {
+				t.Fatalf("p_doc: got %#v", vals[i])
+			}
+			lob, ok := out.Dest.(*godror.Lob)
+			if !ok || !lob.IsClob {
+				t.Fatalf("p_doc: got %#v", out.Dest)
+			}
+			lob.Reader = strings.NewReader(doc)
+		}
+		for _, f := range p.fills {
+			if err := f(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if len(p.streams) != 1 || p.lobs != 1 {
+			t.Fatalf("got %d streams", len(p.streams))
+		}
+		var got []string
+		for {
+			output := dynamicpb.NewMessage(m.desc.Output())
+			err := p.streams[0].next(output, batchSize)
+			got = append(got, output.Get(field(output, "p_doc")).String())
+			if err != nil {
+				break
+			}
+		}
+		if len(got) != 4 || got[0] != doc[:16] || strings.Join(got, "") != doc {
+			t.Errorf("got %q", got)
+		}
+	})
+}
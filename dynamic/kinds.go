@@ -0,0 +1,363 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dynamic
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godror/godror"
+	oracall "github.com/tgulacsi/oracall/lib"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// kind is the Go type a simple value is bound as.
+type kind uint8
+
+const (
+	kString = kind(iota)
+	kNumber // a NUMBER in a string field
+	kInt32
+	kInt64
+	kFloat
+	kBool
+	kBytes
+	kTime
+	kClob
+	kBlob
+)
+
+const timestampName = "google.protobuf.Timestamp"
+
+// kindOf returns the kind of arg, generated as the field fd.
+func kindOf(arg oracall.Argument, fd protoreflect.FieldDescriptor) (kind, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		switch {
+		case arg.Type == "CLOB":
+			return kClob, nil
+		case arg.Type == "NUMBER" || arg.Type == "FLOAT" || arg.Type == "INTEGER":
+			return kNumber, nil
+		}
+		return kString, nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return kInt32, nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return kInt64, nil
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return kFloat, nil
+	case protoreflect.BoolKind:
+		return kBool, nil
+	case protoreflect.BytesKind:
+		if arg.Type == "BLOB" {
+			return kBlob, nil
+		}
+		return kBytes, nil
+	case protoreflect.MessageKind:
+		if fd.Message().FullName() == timestampName {
+			return kTime, nil
+		}
+	}
+	return 0, fmt.Errorf("%s (%s) as %s: %w", arg.Name, arg.Type, fd.Kind(), oracall.ErrUnsupported)
+}
+
+// toOra returns the value to be bound.
+func (k kind) toOra(v protoreflect.Value) any {
+	switch k {
+	case kNumber:
+		return godror.Number(v.String())
+	case kInt32:
+		return int32(v.Int())
+	case kInt64:
+		return v.Int()
+	case kFloat:
+		return v.Float()
+	case kBool:
+		return v.Bool()
+	case kBytes:
+		return v.Bytes()
+	case kTime:
+		m := v.Message()
+		if !m.IsValid() {
+			return nil
+		}
+		fields := m.Descriptor().Fields()
+		return time.Unix(m.Get(fields.ByName("seconds")).Int(), m.Get(fields.ByName("nanos")).Int())
+	case kClob:
+		return godror.Lob{IsClob: true, Reader: strings.NewReader(v.String())}
+	case kBlob:
+		return godror.Lob{Reader: bytes.NewReader(v.Bytes())}
+	default:
+		return v.String()
+	}
+}
+
+// newDest returns a pointer to the OUT variable, set to v (if not nil).
+func (k kind) newDest(v any) any {
+	switch k {
+	case kNumber:
+		p := new(godror.Number)
+		if v != nil {
+			*p = v.(godror.Number)
+		}
+		return p
+	case kInt32:
+		p := new(int32)
+		if v != nil {
+			*p = v.(int32)
+		}
+		return p
+	case kInt64:
+		p := new(int64)
+		if v != nil {
+			*p = v.(int64)
+		}
+		return p
+	case kFloat:
+		p := new(float64)
+		if v != nil {
+			*p = v.(float64)
+		}
+		return p
+	case kBool:
+		p := new(bool)
+		if v != nil {
+			*p = v.(bool)
+		}
+		return p
+	case kBytes:
+		p := new([]byte)
+		if v != nil {
+			*p = v.([]byte)
+		}
+		return p
+	case kTime:
+		p := new(time.Time)
+		if v != nil {
+			*p = v.(time.Time)
+		}
+		return p
+	case kClob, kBlob:
+		p := &godror.Lob{IsClob: k == kClob}
+		if v != nil {
+			*p = v.(godror.Lob)
+		}
+		return p
+	default:
+		p := new(string)
+		if v != nil {
+			*p = v.(string)
+		}
+		return p
+	}
+}
+
+// newSlice returns a pointer to the slice of the values, with capacity for at least size elements.
+func (k kind) newSlice(vals []any, size int) any {
+	size = max(size, len(vals))
+	switch k {
+	case kNumber:
+		return fillSlice(make([]godror.Number, len(vals), size), vals)
+	case kInt32:
+		return fillSlice(make([]int32, len(vals), size), vals)
+	case kInt64:
+		return fillSlice(make([]int64, len(vals), size), vals)
+	case kFloat:
+		return fillSlice(make([]float64, len(vals), size), vals)
+	case kBool:
+		return fillSlice(make([]bool, len(vals), size), vals)
+	case kBytes:
+		return fillSlice(make([][]byte, len(vals), size), vals)
+	case kTime:
+		return fillSlice(make([]time.Time, len(vals), size), vals)
+	default:
+		return fillSlice(make([]string, len(vals), size), vals)
+	}
+}
+
+func fillSlice[T any](s []T, vals []any) *[]T {
+	for i, v := range vals {
+		if v != nil {
+			s[i] = v.(T)
+		}
+	}
+	return &s
+}
+
+// elems returns the elements of the slice returned by newSlice.
+func elems(p any) []any {
+	switch s := p.(type) {
+	case *[]godror.Number:
+		return anySlice(*s)
+	case *[]int32:
+		return anySlice(*s)
+	case *[]int64:
+		return anySlice(*s)
+	case *[]float64:
+		return anySlice(*s)
+	case *[]bool:
+		return anySlice(*s)
+	case *[][]byte:
+		return anySlice(*s)
+	case *[]time.Time:
+		return anySlice(*s)
+	case *[]string:
+		return anySlice(*s)
+	}
+	return nil
+}
+
+func anySlice[T any](s []T) []any {
+	vals := make([]any, len(s))
+	for i, v := range s {
+		vals[i] = v
+	}
+	return vals
+}
+
+// deref returns the value p points to.
+func deref(p any) any {
+	switch x := p.(type) {
+	case *string:
+		return *x
+	case *godror.Number:
+		return *x
+	case *int32:
+		return *x
+	case *int64:
+		return *x
+	case *float64:
+		return *x
+	case *bool:
+		return *x
+	case *[]byte:
+		return *x
+	case *time.Time:
+		return *x
+	case *godror.Lob:
+		return x
+	case *[]godror.Number:
+		return *x
+	case *[]int32:
+		return *x
+	case *[]int64:
+		return *x
+	case *[]float64:
+		return *x
+	case *[]bool:
+		return *x
+	case *[][]byte:
+		return *x
+	case *[]time.Time:
+		return *x
+	case *[]string:
+		return *x
+	}
+	return p
+}
+
+// fromOra converts the returned value (an OUT variable or a cursor column) to the value of fd.
+// ok is false for NULL.
+func (k kind) fromOra(v driver.Value, fd protoreflect.FieldDescriptor) (val protoreflect.Value, ok bool, err error) {
+	if r, isReader := v.(io.Reader); isReader {
+		if lob, isLob := v.(*godror.Lob); isLob && lob.Reader == nil {
+			return val, false, nil
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return val, false, err
+		}
+		v = b
+	}
+	var s string
+	switch x := v.(type) {
+	case nil:
+		return val, false, nil
+	case string:
+		s = x
+	case godror.Number:
+		s = string(x)
+	case []byte:
+		if k == kBytes || k == kBlob {
+			return protoreflect.ValueOfBytes(x), len(x) != 0, nil
+		}
+		s = string(x)
+	case int32:
+		return intValue(int64(x), fd), true, nil
+	case int64:
+		if k == kInt32 || k == kInt64 {
+			return intValue(x, fd), true, nil
+		}
+		s = strconv.FormatInt(x, 10)
+	case float64:
+		if k == kFloat {
+			return floatValue(x, fd), true, nil
+		}
+		s = strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return protoreflect.ValueOfBool(x), true, nil
+	case time.Time:
+		if x.IsZero() {
+			return val, false, nil
+		}
+		if k != kTime {
+			return val, false, fmt.Errorf("%v: %w", x, oracall.ErrUnsupported)
+		}
+		m := dynamicpb.NewMessage(fd.Message())
+		fields := m.Descriptor().Fields()
+		m.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(x.Unix()))
+		m.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(int32(x.Nanosecond())))
+		return protoreflect.ValueOfMessage(m), true, nil
+	default:
+		s = fmt.Sprintf("%v", v)
+	}
+	if s == "" {
+		return val, false, nil
+	}
+	switch k {
+	case kInt32, kInt64:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return val, false, err
+		}
+		return intValue(i, fd), true, nil
+	case kFloat:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return val, false, err
+		}
+		return floatValue(f, fd), true, nil
+	case kBool:
+		b, err := strconv.ParseBool(s)
+		return protoreflect.ValueOfBool(b), err == nil, err
+	case kBytes, kBlob:
+		return protoreflect.ValueOfBytes([]byte(s)), true, nil
+	case kTime:
+		return val, false, fmt.Errorf("%q as time: %w", s, oracall.ErrUnsupported)
+	}
+	return protoreflect.ValueOfString(s), true, nil
+}
+
+func intValue(i int64, fd protoreflect.FieldDescriptor) protoreflect.Value {
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(i))
+	}
+	return protoreflect.ValueOfInt64(i)
+}
+
+func floatValue(f float64, fd protoreflect.FieldDescriptor) protoreflect.Value {
+	if fd.Kind() == protoreflect.FloatKind {
+		return protoreflect.ValueOfFloat32(float32(f))
+	}
+	return protoreflect.ValueOfFloat64(f)
+}
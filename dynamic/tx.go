@@ -0,0 +1,51 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dynamic
+
+import (
+	"time"
+
+	oracall "github.com/tgulacsi/oracall/lib"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// handleTx serves the Begin, Commit and Rollback methods of a <Pkg>Tx service.
+func (s *Server) handleTx(stream grpc.ServerStream, md protoreflect.MethodDescriptor) error {
+	input := dynamicpb.NewMessage(md.Input())
+	if err := stream.RecvMsg(input); err != nil {
+		return err
+	}
+	ctx := stream.Context()
+	in := input.Descriptor().Fields().ByName
+	output := dynamicpb.NewMessage(md.Output())
+	out := output.Descriptor().Fields().ByName
+	var id string
+	var err error
+	switch md.Name() {
+	case "Begin":
+		var idle time.Duration
+		id, idle, err = s.Transactions.Begin(ctx, s.DB, time.Duration(input.Get(in("idle_seconds")).Uint())*time.Second,
+			oracall.TxMode{ReadOnly: input.Get(in("read_only")).Bool(), Serializable: input.Get(in("serializable")).Bool()})
+		output.Set(out("idle_seconds"), protoreflect.ValueOfUint32(uint32(idle/time.Second)))
+	case "Commit":
+		id = input.Get(in("id")).String()
+		err = s.Transactions.Commit(ctx, id)
+	case "Rollback":
+		id = input.Get(in("id")).String()
+		err = s.Transactions.Rollback(ctx, id)
+	default:
+		return status.Errorf(codes.Unimplemented, "unknown method %s", md.FullName())
+	}
+	if err != nil {
+		return err
+	}
+	output.Set(out("id"), protoreflect.ValueOfString(id))
+	return stream.SendMsg(output)
+}
@@ -0,0 +1,82 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/godror/godror"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// DbmsOutputMetadataKey is the gRPC metadata key requesting the DBMS_OUTPUT of the call ("1" or "true").
+	DbmsOutputMetadataKey = "x-oracall-dbms-output"
+	// DbmsOutputTrailerKey is the trailer key of the DBMS_OUTPUT lines (binary, as they may be any UTF-8).
+	DbmsOutputTrailerKey = "x-oracall-dbms-output-bin"
+)
+
+// MaxDbmsOutput limits the size of the DBMS_OUTPUT returned in the trailer, as the trailers are limited, too.
+var MaxDbmsOutput = 8 << 10
+
+// DbmsOutputRequested reports whether the incoming metadata asks for the DBMS_OUTPUT of the call.
+func DbmsOutputRequested(ctx context.Context) bool {
+	vv := metadata.ValueFromIncomingContext(ctx, DbmsOutputMetadataKey)
+	if len(vv) == 0 {
+		return false
+	}
+	ok, _ := strconv.ParseBool(vv[0])
+	return ok
+}
+
+type execPreparer interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+}
+
+// EnableDbmsOutput enables DBMS_OUTPUT in the session of tx, if enabled or DbmsOutputRequested.
+//
+// The returned function (nil if not enabled) reads the lines written since - before the transaction ends -,
+// and sets them as the DbmsOutputTrailerKey trailer of the gRPC call.
+// It disables DBMS_OUTPUT (clearing its buffer), so it must be called on the error paths, too:
+// the session goes back to the pool, and the next calls in it should neither fill nor see the buffer.
+func EnableDbmsOutput(ctx context.Context, tx execPreparer, enabled bool) (func(context.Context) ([]string, error), error) {
+	if !enabled && !DbmsOutputRequested(ctx) {
+		return nil, nil
+	}
+	if err := godror.EnableDbmsOutput(ctx, tx); err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) ([]string, error) {
+		var buf strings.Builder
+		err := godror.ReadDbmsOutput(ctx, &buf, tx)
+		if _, disErr := tx.ExecContext(ctx, "BEGIN DBMS_OUTPUT.disable; END;"); err == nil {
+			err = disErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		s := strings.TrimSuffix(buf.String(), "\n")
+		if s == "" {
+			return nil, nil
+		}
+		lines := strings.Split(s, "\n")
+		trailer, size := make([]string, 0, len(lines)), 0
+		for _, line := range lines {
+			if size += len(line); size > MaxDbmsOutput {
+				trailer = append(trailer, "...")
+				break
+			}
+			trailer = append(trailer, line)
+		}
+		// outside of a gRPC call there is no trailer to set
+		_ = grpc.SetTrailer(ctx, metadata.MD{DbmsOutputTrailerKey: trailer})
+		return lines, nil
+	}, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestDbmsOutput(t *testing.T) {
+	ctx := context.Background()
+	if read, err := EnableDbmsOutput(ctx, nil, false); read != nil || err != nil {
+		t.Errorf("not asked for: got %p, %+v", read, err)
+	}
+	for v, want := range map[string]bool{"true": true, "1": true, "0": false, "x": false} {
+		if got := DbmsOutputRequested(metadata.NewIncomingContext(ctx, metadata.Pairs(DbmsOutputMetadataKey, v))); got != want {
+			t.Errorf("%q: got %t", v, got)
+		}
+	}
+
+	id := UserArgument{PackageName: "PKG", ObjectName: "CALC", ObjectID: 1, SubprogramID: 10,
+		ArgumentName: "P_ID", InOut: "IN", DataType: "NUMBER", PlsType: "NUMBER"}
+	functions := ParseArgumentsIter(slices.Values([][]UserArgument{{id}}), nil)
+	if _, callFun := functions[0].PlsqlBlock(""); !strings.Contains(callFun, "oracall.EnableDbmsOutput(ctx, tx, s.DbmsOutput)") ||
+		!strings.Contains(callFun, `logger.Debug("dbms_output"`) {
+		t.Errorf("got %s", callFun)
+	}
+}
+
+func TestDbmsOutputSession(t *testing.T) {
+	db := sql.OpenDB(&outputConn{})
+	defer db.Close()
+	db.SetMaxOpenConns(1) // all calls share the session
+	ctx := context.Background()
+	call := func(line string, enabled bool) []string {
+		t.Helper()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Commit()
+		read, err := EnableDbmsOutput(ctx, tx, enabled)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = tx.ExecContext(ctx, "put_line:"+line); err != nil {
+			t.Fatal(err)
+		}
+		if read == nil {
+			return nil
+		}
+		lines, err := read(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return lines
+	}
+	if got := call("first", true); !slices.Equal(got, []string{"first"}) {
+		t.Errorf("first: got %q", got)
+	}
+	call("second", false)
+	if got := call("third", true); !slices.Equal(got, []string{"third"}) {
+		t.Errorf("third: got %q", got)
+	}
+}
+
+// outputConn is a fake session with DBMS_OUTPUT.
+type outputConn struct {
+	enabled bool
+	buf     []string
+}
+
+func (c *outputConn) Connect(context.Context) (driver.Conn, error) { return c, nil }
+func (c *outputConn) Driver() driver.Driver                        { return nil }
+func (c *outputConn) Prepare(qry string) (driver.Stmt, error)      { return outputStmt{c: c, qry: qry}, nil }
+func (c *outputConn) Close() error                                 { return nil }
+func (c *outputConn) Begin() (driver.Tx, error)                    { return c, nil }
+func (c *outputConn) Commit() error                                { return nil }
+func (c *outputConn) Rollback() error                              { return nil }
+
+type outputStmt struct {
+	c   *outputConn
+	qry string
+}
+
+func (st outputStmt) Close() error                             { return nil }
+func (st outputStmt) NumInput() int                            { return -1 }
+func (st outputStmt) CheckNamedValue(*driver.NamedValue) error { return nil }
+func (st outputStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.ErrUnsupported
+}
+func (st outputStmt) Exec(args []driver.Value) (driver.Result, error) {
+	c := st.c
+	switch qry := st.qry; {
+	case strings.Contains(qry, "DBMS_OUTPUT.enable("):
+		c.enabled = true
+	case strings.Contains(qry, "DBMS_OUTPUT.disable"):
+		c.enabled, c.buf = false, nil
+	case strings.Contains(qry, "DBMS_OUTPUT.get_lines("):
+		lines, numLines := args[1].(sql.Out).Dest.(*[]string), args[2].(sql.Out).Dest.(*int64)
+		n := copy(*lines, c.buf)
+		c.buf, *numLines = c.buf[n:], int64(n)
+	case strings.HasPrefix(qry, "put_line:"):
+		if c.enabled {
+			c.buf = append(c.buf, strings.TrimPrefix(qry, "put_line:"))
+		}
+	default:
+		return nil, errors.ErrUnsupported
+	}
+	return driver.RowsAffected(0), nil
+}
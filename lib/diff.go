@@ -0,0 +1,212 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// ChangeKind is the kind of an API change between two metadata snapshots.
+type ChangeKind string
+
+const (
+	ChangeAdded     = ChangeKind("added")
+	ChangeRemoved   = ChangeKind("removed")
+	ChangeType      = ChangeKind("type")
+	ChangeDirection = ChangeKind("direction")
+)
+
+// Change is one difference between the old and the new version of a function.
+type Change struct {
+	Function string
+	// Argument is the dotted path of the argument (record field, cursor column),
+	// empty for function-level changes.
+	Argument string     `json:",omitzero"`
+	Kind     ChangeKind `json:",omitzero"`
+	Old      string     `json:",omitzero"`
+	New      string     `json:",omitzero"`
+	Breaking bool
+}
+
+func (c Change) String() string {
+	compat := "compatible"
+	if c.Breaking {
+		compat = "BREAKING"
+	}
+	s := compat + "\t" + c.Function
+	if c.Argument != "" {
+		s += "\t" + c.Argument
+	}
+	s += "\t" + string(c.Kind)
+	switch {
+	case c.Old != "" && c.New != "":
+		s += "\t" + c.Old + " -> " + c.New
+	case c.Old != "" || c.New != "":
+		s += "\t" + c.Old + c.New
+	}
+	return s
+}
+
+// DiffFunctions compares the old and the new functions, and classifies each change
+// as compatible or breaking for the generated proto/Go API.
+func DiffFunctions(old, new []Function) []Change {
+	L := strings.ToLower
+	oldM := make(map[string]Function, len(old))
+	for _, f := range old {
+		oldM[L(f.Name())] = f
+	}
+	newM := make(map[string]Function, len(new))
+	for _, f := range new {
+		newM[L(f.Name())] = f
+	}
+
+	var changes []Change
+	for k, o := range oldM {
+		n, ok := newM[k]
+		if !ok {
+			changes = append(changes, Change{Function: o.Name(), Kind: ChangeRemoved, Breaking: true})
+			continue
+		}
+		oArgs, nArgs := o.Args, n.Args
+		if o.Returns != nil {
+			oArgs = append(slices.Clip(oArgs), *o.Returns)
+		}
+		if n.Returns != nil {
+			nArgs = append(slices.Clip(nArgs), *n.Returns)
+		}
+		changes = diffArgs(changes, o.Name(), "", oArgs, nArgs)
+	}
+	for k, n := range newM {
+		if _, ok := oldM[k]; !ok {
+			changes = append(changes, Change{Function: n.Name(), Kind: ChangeAdded})
+		}
+	}
+	slices.SortFunc(changes, func(a, b Change) int {
+		if c := strings.Compare(a.Function, b.Function); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Argument, b.Argument)
+	})
+	return changes
+}
+
+// HasBreaking reports whether any of the changes is breaking.
+func HasBreaking(changes []Change) bool {
+	return slices.ContainsFunc(changes, func(c Change) bool { return c.Breaking })
+}
+
+// WriteChanges writes the changes as text, one per line.
+func WriteChanges(w io.Writer, changes []Change) error {
+	for _, c := range changes {
+		if _, err := fmt.Fprintln(w, c.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func diffArgs(changes []Change, funName, prefix string, old, new []Argument) []Change {
+	path := func(a Argument) string {
+		if prefix == "" {
+			return a.Name
+		}
+		return prefix + "." + a.Name
+	}
+	newM := make(map[string]Argument, len(new))
+	for _, a := range new {
+		newM[a.Name] = a
+	}
+	oldM := make(map[string]struct{}, len(old))
+	for _, o := range old {
+		oldM[o.Name] = struct{}{}
+		n, ok := newM[o.Name]
+		if !ok {
+			changes = append(changes, Change{
+				Function: funName, Argument: path(o), Kind: ChangeRemoved,
+				Old: o.OracleName(), Breaking: true,
+			})
+			continue
+		}
+		changes = diffArg(changes, funName, path(o), o, n)
+	}
+	for _, n := range new {
+		if _, ok := oldM[n.Name]; !ok {
+			// A new field is ignored by old clients, and not sent by them.
+			changes = append(changes, Change{
+				Function: funName, Argument: path(n), Kind: ChangeAdded,
+				New: n.OracleName(),
+			})
+		}
+	}
+	return changes
+}
+
+func diffArg(changes []Change, funName, path string, o, n Argument) []Change {
+	if o.Direction != n.Direction {
+		// gaining a direction only adds a field to the other message,
+		// losing one removes a field.
+		changes = append(changes, Change{
+			Function: funName, Argument: path, Kind: ChangeDirection,
+			Old: o.Direction.String(), New: n.Direction.String(),
+			Breaking: o.Direction&n.Direction != o.Direction,
+		})
+	}
+	if o.Flavor != n.Flavor || o.Type != n.Type && !(o.Flavor == FLAVOR_SIMPLE && n.Flavor == FLAVOR_SIMPLE) {
+		return append(changes, Change{
+			Function: funName, Argument: path, Kind: ChangeType,
+			Old: o.OracleName(), New: n.OracleName(), Breaking: true,
+		})
+	}
+	switch o.Flavor {
+	case FLAVOR_SIMPLE:
+		if o.OracleName() != n.OracleName() {
+			// Same wire type is compatible (e.g. VARCHAR2(10) -> VARCHAR2(20)).
+			changes = append(changes, Change{
+				Function: funName, Argument: path, Kind: ChangeType,
+				Old: o.OracleName(), New: n.OracleName(),
+				Breaking: wireType(o) != wireType(n),
+			})
+		}
+	case FLAVOR_RECORD:
+		changes = diffArgs(changes, funName, path, recordArgs(o.RecordOf), recordArgs(n.RecordOf))
+	case FLAVOR_TABLE:
+		if o.TableOf == nil || n.TableOf == nil {
+			break
+		}
+		if o.TableOf.Flavor == FLAVOR_RECORD && n.TableOf.Flavor == FLAVOR_RECORD {
+			changes = diffArgs(changes, funName, path, recordArgs(o.TableOf.RecordOf), recordArgs(n.TableOf.RecordOf))
+		} else {
+			changes = diffArg(changes, funName, path+"[]", *o.TableOf, *n.TableOf)
+		}
+	}
+	return changes
+}
+
+func recordArgs(nas []NamedArgument) []Argument {
+	args := make([]Argument, 0, len(nas))
+	for _, na := range nas {
+		a := *na.Argument
+		a.Name = na.Name
+		args = append(args, a)
+	}
+	return args
+}
+
+// wireType returns the protobuf type the argument is generated as.
+func wireType(a Argument) string {
+	got, err := a.goType(false)
+	if err != nil {
+		return a.Type
+	}
+	got = strings.TrimPrefix(strings.TrimPrefix(got, "*"), "[]")
+	if got == "byte" {
+		return "bytes"
+	}
+	typ, _ := protoType(strings.TrimPrefix(got, "*"), a.Name, a.AbsType)
+	return typ
+}
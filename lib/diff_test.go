@@ -0,0 +1,59 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"testing"
+)
+
+func TestDiffFunctions(t *testing.T) {
+	const head = "OBJECT_ID;SUBPROGRAM_ID;PACKAGE_NAME;OBJECT_NAME;DATA_LEVEL;POSITION;ARGUMENT_NAME;IN_OUT;DATA_TYPE;DATA_PRECISION;DATA_SCALE;CHARACTER_SET_NAME;PLS_TYPE;CHAR_LENGTH;TYPE_LINK;TYPE_OWNER;TYPE_NAME;TYPE_SUBNAME\n"
+	old := testCase{Csv: head + `19734;35;DB_WEB;SENDPREOFFER_31101;0;1;P_SESSIONID;IN;VARCHAR2;;;CHAR_CS;VARCHAR2;10;;;;
+19734;35;DB_WEB;SENDPREOFFER_31101;0;2;P_LANG;IN;VARCHAR2;;;CHAR_CS;VARCHAR2;;;;;
+19734;35;DB_WEB;SENDPREOFFER_31101;0;3;P_VEGLEGES;IN;VARCHAR2;;;CHAR_CS;VARCHAR2;;;;;
+19734;35;DB_WEB;SENDPREOFFER_31101;0;4;P_VONALKOD;OUT;BINARY_INTEGER;;;;PLS_INTEGER;0;;;;
+19734;36;DB_WEB;LOGOUT;0;1;P_SESSIONID;IN;VARCHAR2;;;CHAR_CS;VARCHAR2;;;;;
+`}.ParseCsv(t, 0)
+	new := testCase{Csv: head + `19734;35;DB_WEB;SENDPREOFFER_31101;0;1;P_SESSIONID;IN;VARCHAR2;;;CHAR_CS;VARCHAR2;20;;;;
+19734;35;DB_WEB;SENDPREOFFER_31101;0;2;P_VEGLEGES;IN/OUT;VARCHAR2;;;CHAR_CS;VARCHAR2;;;;;
+19734;35;DB_WEB;SENDPREOFFER_31101;0;3;P_VONALKOD;OUT;VARCHAR2;;;CHAR_CS;VARCHAR2;;;;;
+19734;35;DB_WEB;SENDPREOFFER_31101;0;4;P_UJ;IN;VARCHAR2;;;CHAR_CS;VARCHAR2;;;;;
+19734;37;DB_WEB;LOGIN;0;1;P_USER;IN;VARCHAR2;;;CHAR_CS;VARCHAR2;;;;;
+`}.ParseCsv(t, 0)
+
+	changes := DiffFunctions(old, new)
+	for _, c := range changes {
+		t.Log(c)
+	}
+	type key struct {
+		Function, Argument string
+		Kind               ChangeKind
+	}
+	got := make(map[key]bool, len(changes))
+	for _, c := range changes {
+		got[key{c.Function, c.Argument, c.Kind}] = c.Breaking
+	}
+	for k, breaking := range map[key]bool{
+		{"DB_web.logout", "", ChangeRemoved}:                         true,
+		{"DB_web.login", "", ChangeAdded}:                            false,
+		{"DB_web.sendpreoffer_31101", "p_sessionid", ChangeType}:     false,
+		{"DB_web.sendpreoffer_31101", "p_lang", ChangeRemoved}:       true,
+		{"DB_web.sendpreoffer_31101", "p_vegleges", ChangeDirection}: false,
+		{"DB_web.sendpreoffer_31101", "p_vonalkod", ChangeType}:      true,
+		{"DB_web.sendpreoffer_31101", "p_uj", ChangeAdded}:           false,
+	} {
+		if b, ok := got[k]; !ok {
+			t.Errorf("%v not found", k)
+		} else if b != breaking {
+			t.Errorf("%v: got breaking=%t, wanted %t", k, b, breaking)
+		}
+	}
+	if !HasBreaking(changes) {
+		t.Error("HasBreaking: false")
+	}
+	if changes = DiffFunctions(old, old); len(changes) != 0 {
+		t.Errorf("same: got %v", changes)
+	}
+}
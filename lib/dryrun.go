@@ -0,0 +1,36 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"context"
+	"strconv"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// DryRunMetadataKey is the gRPC metadata key requesting a dry run ("1" or "true").
+const DryRunMetadataKey = "x-oracall-dry-run"
+
+type dryRunCtxKey struct{}
+
+// ContextWithDryRun returns a context whose calls are executed fully, but rolled back instead of committed.
+func ContextWithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunCtxKey{}, true)
+}
+
+// IsDryRun reports whether the calls of the context are dry runs:
+// by ContextWithDryRun, or by the DryRunMetadataKey of the incoming metadata.
+func IsDryRun(ctx context.Context) bool {
+	if dryRun, ok := ctx.Value(dryRunCtxKey{}).(bool); ok {
+		return dryRun
+	}
+	vv := metadata.ValueFromIncomingContext(ctx, DryRunMetadataKey)
+	if len(vv) == 0 {
+		return false
+	}
+	dryRun, _ := strconv.ParseBool(vv[0])
+	return dryRun
+}
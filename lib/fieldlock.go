@@ -0,0 +1,148 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+	"github.com/google/renameio/v2"
+)
+
+type (
+	// FieldLock records the protobuf field numbers assigned to each message field,
+	// so regenerating the .proto keeps the wire format of already deployed clients.
+	FieldLock struct {
+		mu       sync.Mutex
+		Messages map[string]*MessageLock `json:",omitempty"`
+	}
+
+	// MessageLock holds the field numbers of one message.
+	MessageLock struct {
+		Fields   map[string]int  `json:",omitempty"`
+		Reserved []ReservedField `json:",omitempty"`
+		// Last is the highest number ever given out in this message.
+		Last int `json:",omitzero"`
+	}
+
+	// ReservedField is a removed field, whose number must not be reused.
+	ReservedField struct {
+		Name   string
+		Number int
+	}
+)
+
+// FieldLockFileName returns the lock file name for the given .proto file.
+func FieldLockFileName(protoFn string) string {
+	return strings.TrimSuffix(protoFn, ".proto") + ".fieldlock.json"
+}
+
+// ReadFieldLock reads the lock file, returns an empty lock if the file does not exist.
+func ReadFieldLock(fn string) (*FieldLock, error) {
+	fl := &FieldLock{Messages: make(map[string]*MessageLock)}
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fl, nil
+		}
+		return nil, err
+	}
+	if err = json.Unmarshal(b, fl); err != nil {
+		return nil, err
+	}
+	if fl.Messages == nil {
+		fl.Messages = make(map[string]*MessageLock)
+	}
+	return fl, nil
+}
+
+// WriteFile writes the lock into fn atomically.
+func (fl *FieldLock) WriteFile(fn string) error {
+	fh, err := renameio.NewPendingFile(fn, renameio.WithPermissions(0644))
+	if err != nil {
+		return err
+	}
+	defer fh.Cleanup()
+	fl.mu.Lock()
+	err = json.MarshalWrite(fh, fl, json.Deterministic(true), jsontext.WithIndent("  "))
+	fl.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if _, err = fh.Write([]byte{'\n'}); err != nil {
+		return err
+	}
+	return fh.CloseAtomicallyReplace()
+}
+
+// assign returns the field numbers for the given field names of msgName,
+// and the reserved fields that has been removed since.
+//
+// Known fields keep their numbers, new fields get fresh numbers,
+// missing fields get reserved.
+// A nil FieldLock numbers the fields by position.
+func (fl *FieldLock) assign(msgName string, names []string) ([]int, []ReservedField) {
+	numbers := make([]int, len(names))
+	if fl == nil {
+		for i := range numbers {
+			numbers[i] = i + 1
+		}
+		return numbers, nil
+	}
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	if fl.Messages == nil {
+		fl.Messages = make(map[string]*MessageLock)
+	}
+	ml := fl.Messages[msgName]
+	if ml == nil {
+		ml = &MessageLock{}
+		fl.Messages[msgName] = ml
+	}
+	if ml.Fields == nil {
+		ml.Fields = make(map[string]int, len(names))
+	}
+	for _, n := range ml.Fields {
+		ml.Last = max(ml.Last, n)
+	}
+	for _, r := range ml.Reserved {
+		ml.Last = max(ml.Last, r.Number)
+	}
+
+	used := make(map[string]struct{}, len(names))
+	for i, nm := range names {
+		used[nm] = struct{}{}
+		n, ok := ml.Fields[nm]
+		if !ok {
+			ml.Last++
+			n = ml.Last
+			ml.Fields[nm] = n
+		}
+		numbers[i] = n
+	}
+	for nm, n := range ml.Fields {
+		if _, ok := used[nm]; !ok {
+			ml.Reserved = append(ml.Reserved, ReservedField{Name: nm, Number: n})
+			delete(ml.Fields, nm)
+		}
+	}
+	slices.SortFunc(ml.Reserved, func(a, b ReservedField) int { return a.Number - b.Number })
+
+	// A re-added field gets a new number, but its name must not be reserved.
+	reserved := make([]ReservedField, 0, len(ml.Reserved))
+	for _, r := range ml.Reserved {
+		if _, ok := used[r.Name]; ok {
+			r.Name = ""
+		}
+		reserved = append(reserved, r)
+	}
+	return numbers, reserved
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"bytes"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFieldLockAssign(t *testing.T) {
+	var fl FieldLock
+	nums, res := fl.assign("M", []string{"a", "b", "c"})
+	if want := []int{1, 2, 3}; !slices.Equal(nums, want) || len(res) != 0 {
+		t.Fatalf("got %v %v, wanted %v", nums, res, want)
+	}
+	// insert x in the middle, remove b
+	nums, res = fl.assign("M", []string{"a", "x", "c"})
+	if want := []int{1, 4, 3}; !slices.Equal(nums, want) {
+		t.Errorf("got %v, wanted %v", nums, want)
+	}
+	if want := []ReservedField{{Name: "b", Number: 2}}; !slices.Equal(res, want) {
+		t.Errorf("got reserved %v, wanted %v", res, want)
+	}
+	// re-add b: new number, 2 stays reserved, but not its name
+	nums, res = fl.assign("M", []string{"a", "b", "x", "c"})
+	if want := []int{1, 5, 4, 3}; !slices.Equal(nums, want) {
+		t.Errorf("got %v, wanted %v", nums, want)
+	}
+	if want := []ReservedField{{Number: 2}}; !slices.Equal(res, want) {
+		t.Errorf("got reserved %v, wanted %v", res, want)
+	}
+
+	var nilLock *FieldLock
+	if nums, _ = nilLock.assign("M", []string{"a", "b"}); !slices.Equal(nums, []int{1, 2}) {
+		t.Errorf("nil lock: got %v", nums)
+	}
+}
+
+func TestFieldLockProtobuf(t *testing.T) {
+	functions := testCase{Csv: `OBJECT_ID;SUBPROGRAM_ID;PACKAGE_NAME;OBJECT_NAME;DATA_LEVEL;POSITION;ARGUMENT_NAME;IN_OUT;DATA_TYPE;DATA_PRECISION;DATA_SCALE;CHARACTER_SET_NAME;PLS_TYPE;CHAR_LENGTH;TYPE_LINK;TYPE_OWNER;TYPE_NAME;TYPE_SUBNAME
+19734;35;DB_WEB;SENDPREOFFER_31101;0;1;P_SESSIONID;IN;VARCHAR2;;;CHAR_CS;VARCHAR2;;;;;
+19734;35;DB_WEB;SENDPREOFFER_31101;0;2;P_LANG;IN;VARCHAR2;;;CHAR_CS;VARCHAR2;;;;;
+19734;35;DB_WEB;SENDPREOFFER_31101;0;3;P_VEGLEGES;IN;VARCHAR2;;;CHAR_CS;VARCHAR2;;;;;
+19734;35;DB_WEB;SENDPREOFFER_31101;0;4;P_VONALKOD;OUT;BINARY_INTEGER;;;;PLS_INTEGER;0;;;;
+`}.ParseCsv(t, 0)
+	fn := filepath.Join(t.TempDir(), "x.fieldlock.json")
+	lock, err := ReadFieldLock(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = SaveProtobufWithLock(t.Context(), &buf, functions, "x", "x", lock); err != nil {
+		t.Fatal(err)
+	}
+	if err = lock.WriteFile(fn); err != nil {
+		t.Fatal(err)
+	}
+	if lock, err = ReadFieldLock(fn); err != nil {
+		t.Fatal(err)
+	}
+
+	// drop p_lang (the 2nd input)
+	f := functions[0]
+	f.Args = slices.Delete(slices.Clone(f.Args), 1, 2)
+	buf.Reset()
+	if err = SaveProtobufWithLock(t.Context(), &buf, []Function{f}, "x", "x", lock); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	t.Log(s)
+	for _, want := range []string{
+		"string p_vegleges = 3;",
+		"reserved 2;",
+		`reserved "p_lang";`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("%q not found", want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"github.com/godror/godror"
+)
+
+// Identity is the identity of the end user the calls are made for.
+type Identity struct {
+	// User is the database user the session is proxied to (ALTER USER user GRANT CONNECT THROUGH pool_user);
+	// empty keeps the user of the pool.
+	User string
+	// ClientID is set as the CLIENT_IDENTIFIER of the session, for auditing and row-level security.
+	ClientID string
+}
+
+// IsZero reports whether the identity is empty.
+func (id Identity) IsZero() bool { return id.User == "" && id.ClientID == "" }
+
+type identityCtxKey struct{}
+
+// ContextWithIdentity returns a context carrying the identity, used by BeginTx.
+func ContextWithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityCtxKey{}, id)
+}
+
+// IdentityFromContext returns the identity of the context.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityCtxKey{}).(Identity)
+	return id, ok && !id.IsZero()
+}
+
+// BeginTx begins a transaction on db, as the Identity of the context, if any.
+//
+// With an Identity.User the session is acquired as that user, through proxy authentication:
+// this needs a heterogeneous pool (heterogeneousPool=1 in the connection string),
+// and the Go connection pool disabled with db.SetMaxIdleConns(0).
+// The Identity.ClientID is set with DBMS_SESSION.SET_IDENTIFIER.
+//
+// The returned function clears the client identifier and releases the session - call it after the transaction has ended.
+func BeginTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (*sql.Tx, func(), error) {
+	id, ok := IdentityFromContext(ctx)
+	if !ok {
+		tx, err := db.BeginTx(ctx, opts)
+		return tx, func() {}, err
+	}
+	if id.User != "" {
+		ctx = godror.ContextWithUserPassw(ctx, "["+id.User+"]", "", "")
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if id.ClientID != "" {
+		if _, err = conn.ExecContext(ctx, "BEGIN DBMS_SESSION.SET_IDENTIFIER(:1); END;", id.ClientID); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	}
+	tx, err := conn.BeginTx(ctx, opts)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return tx, func() {
+		if id.ClientID != "" {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+			defer cancel()
+			if _, err := conn.ExecContext(ctx, "BEGIN DBMS_SESSION.CLEAR_IDENTIFIER; END;"); err != nil {
+				// do not give back the session with the identifier of somebody else
+				conn.Raw(func(any) error { return driver.ErrBadConn })
+			}
+		}
+		conn.Close()
+	}, nil
+}
@@ -33,7 +33,9 @@ type ProcParameter struct {
 // Procedure is a stored procedure with its parameters.
 type Procedure struct {
 	Owner, Package, Name string
-	Parameters           []ProcParameter
+	// Overload is the overload number ("1", "2", ...) of an overloaded procedure, empty otherwise.
+	Overload   string
+	Parameters []ProcParameter
 }
 
 // Procedures is a list of stored procedures.
@@ -59,7 +61,7 @@ func (p Procedure) FullName() string {
 // If funcNames is provided, only those procedures are returned.
 func ReadProcedures(ctx context.Context, db querier, funcNames ...string) (*Procedures, error) {
 	const qry = `
-SELECT a.owner, a.package_name, a.object_name,
+SELECT a.owner, a.package_name, a.object_name, a.overload,
        a.argument_name, a.in_out,
        CASE
          WHEN a.type_owner IS NOT NULL THEN
@@ -88,9 +90,9 @@ ORDER BY a.object_id, a.overload, a.subprogram_id, a.sequence`
 	var currentProc *Procedure
 
 	for rows.Next() {
-		var owner, packageName, objectName, argName, inOut, oraType string
+		var owner, packageName, objectName, overload, argName, inOut, oraType string
 		var pos int
-		if err := rows.Scan(&owner, &packageName, &objectName, &argName, &inOut, &oraType, &pos); err != nil {
+		if err := rows.Scan(&owner, &packageName, &objectName, &overload, &argName, &inOut, &oraType, &pos); err != nil {
 			return nil, fmt.Errorf("scan: %w", err)
 		}
 		if len(funcNames) > 0 {
@@ -105,12 +107,12 @@ ORDER BY a.object_id, a.overload, a.subprogram_id, a.sequence`
 				continue
 			}
 		}
-		key := owner + "." + packageName + "." + objectName
+		key := owner + "." + packageName + "." + objectName + "#" + overload
 		if key != currentKey {
 			if currentProc != nil {
 				procs.Items = append(procs.Items, *currentProc)
 			}
-			currentProc = &Procedure{Owner: owner, Package: packageName, Name: objectName}
+			currentProc = &Procedure{Owner: owner, Package: packageName, Name: objectName, Overload: overload}
 			currentKey = key
 		}
 		currentProc.Parameters = append(currentProc.Parameters, ProcParameter{
@@ -188,24 +190,34 @@ func (p Procedure) needsTimeImport(types *Types) bool {
 	return false
 }
 
+// baseName returns the package and the procedure name, suffixed with the overload number (if any).
+// The call uses named notation, so the DB chooses the overload.
+func (p Procedure) baseName() string {
+	nm := p.Package + "_" + p.Name
+	if p.Overload != "" {
+		nm += "_" + p.Overload
+	}
+	return strings.ReplaceAll(nm, ".", "_")
+}
+
 // goInputStructName returns the Go input struct name for this procedure.
 func (p Procedure) goInputStructName() string {
-	return oracall.CamelCase(strings.ReplaceAll(p.Package+"_"+p.Name, ".", "_")) + "Input"
+	return oracall.CamelCase(p.baseName()) + "Input"
 }
 
 // goOutputStructName returns the Go output struct name for this procedure.
 func (p Procedure) goOutputStructName() string {
-	return oracall.CamelCase(strings.ReplaceAll(p.Package+"_"+p.Name, ".", "_")) + "Output"
+	return oracall.CamelCase(p.baseName()) + "Output"
 }
 
 // goFuncName returns the exported Go function name for this procedure.
 func (p Procedure) goFuncName() string {
-	return oracall.CamelCase(strings.ReplaceAll(p.Package+"_"+p.Name, ".", "_"))
+	return oracall.CamelCase(p.baseName())
 }
 
 // constName returns the Go const name for the PL/SQL call text.
 func (p Procedure) constName() string {
-	return strings.ToLower(strings.ReplaceAll(p.Package+"_"+p.Name, ".", "_")) + "_plsql"
+	return strings.ToLower(p.baseName()) + "_plsql"
 }
 
 // WriteProcedureWrapper generates Go code for calling this stored procedure
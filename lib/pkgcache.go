@@ -24,8 +24,20 @@ import (
 type (
 	FunctionCache struct {
 		Name          string         `json:",omitzero"`
+		Overload      string         `json:",omitzero"`
 		Documentation string         `json:",omitzero"`
 		Arguments     []UserArgument `json:",omitempty"`
+		// Cursors holds the described columns of the weakly typed (SYS_REFCURSOR) arguments,
+		// by argument name ("" for the return value).
+		Cursors map[string][]UserArgument `json:",omitempty"`
+		// Results holds the described implicit result sets (DBMS_SQL.RETURN_RESULT), in order.
+		Results []ResultCache `json:",omitempty"`
+	}
+
+	// ResultCache is a described implicit result set.
+	ResultCache struct {
+		Name    string
+		Columns []UserArgument
 	}
 
 	// PackageCache holds everything known about one DB package, serializable to/from JSON.
@@ -38,6 +50,70 @@ type (
 	}
 )
 
+// AllArguments returns the arguments, with the described columns of the weak cursors,
+// and the implicit result sets.
+func (f FunctionCache) AllArguments() []UserArgument {
+	return AppendResults(ExpandCursors(f.Arguments, f.Cursors), f.Results)
+}
+
+// AppendResults appends the implicit result sets to the arguments of the function, as OUT cursors.
+func AppendResults(uas []UserArgument, results []ResultCache) []UserArgument {
+	if len(results) == 0 || len(uas) == 0 {
+		return uas
+	}
+	base := uas[0]
+	for _, r := range results {
+		cur := UserArgument{
+			PackageName: base.PackageName, ObjectName: base.ObjectName, Overload: base.Overload,
+			LastDDL: base.LastDDL, ArgumentName: strings.ToUpper(r.Name), InOut: "OUT",
+			DataType: "REF CURSOR", PlsType: "REF CURSOR",
+			ObjectID: base.ObjectID, SubprogramID: base.SubprogramID, Position: uint(len(uas)),
+			Implicit: true,
+		}
+		uas = append(uas, ExpandCursors(
+			[]UserArgument{cur},
+			map[string][]UserArgument{cur.ArgumentName: r.Columns})...)
+	}
+	return uas
+}
+
+// ExpandCursors inserts the described columns after the REF CURSOR arguments without a row type,
+// as a record - the same way the DB lists the strongly typed cursors.
+func ExpandCursors(uas []UserArgument, cursors map[string][]UserArgument) []UserArgument {
+	if len(cursors) == 0 {
+		return uas
+	}
+	expanded := make([]UserArgument, 0, len(uas)+8)
+	for i, ua := range uas {
+		expanded = append(expanded, ua)
+		cols := cursors[strings.ToUpper(ua.ArgumentName)]
+		if ua.DataType != "REF CURSOR" || len(cols) == 0 ||
+			i+1 < len(uas) && uas[i+1].DataLevel > ua.DataLevel { // strongly typed
+			continue
+		}
+		nm := ua.ArgumentName
+		if nm == "" {
+			nm = "RET"
+		}
+		sub := strings.ToUpper(ua.FunctionKey() + "_" + nm + "_ROW")
+		rec := UserArgument{
+			PackageName: ua.PackageName, ObjectName: ua.ObjectName, Overload: ua.Overload,
+			LastDDL: ua.LastDDL, InOut: ua.InOut, DataType: "PL/SQL RECORD",
+			PlsType: ua.PackageName + "." + sub, TypeName: ua.PackageName, TypeSubname: sub,
+			ObjectID: ua.ObjectID, SubprogramID: ua.SubprogramID, Position: ua.Position,
+			DataLevel: ua.DataLevel + 1,
+		}
+		expanded = append(expanded, rec)
+		for _, c := range cols {
+			c.PackageName, c.ObjectName, c.Overload, c.LastDDL = rec.PackageName, rec.ObjectName, rec.Overload, rec.LastDDL
+			c.InOut, c.ObjectID, c.SubprogramID, c.Position = rec.InOut, rec.ObjectID, rec.SubprogramID, rec.Position
+			c.DataLevel = rec.DataLevel + 1
+			expanded = append(expanded, c)
+		}
+	}
+	return expanded
+}
+
 // WritePackageCache writes pc to dir/{PackageName}.json atomically.
 func WritePackageCache(ctx context.Context, dir string, pc PackageCache) error {
 	logger := zlog.SFromContext(ctx)
@@ -133,31 +209,39 @@ func ParsePackageCaches(ctx context.Context, dir string, filter func(string) boo
 			packages[pc.Name] = pc.Documentation
 		}
 		annotations = append(annotations, pc.Annotations...)
-		fns := ParseArgumentsIter(
-			FilterAndGroupIter(func(yield func(UserArgument) bool) {
-				for _, f := range pc.Functions {
-					for _, ua := range f.Arguments {
-						if !yield(ua) {
-							return
-						}
+		functions = append(functions, ParsePackageCache(ctx, pc, filter)...)
+		logger.Debug("parse", "pkg", pkgName, "functions", functions)
+	}
+	return packages, functions, annotations, nil
+}
+
+// ParsePackageCache parses the arguments of pc into Functions.
+func ParsePackageCache(ctx context.Context, pc PackageCache, filter func(string) bool) []Function {
+	logger := zlog.SFromContext(ctx)
+	fns := ParseArgumentsIter(
+		FilterAndGroupIter(func(yield func(UserArgument) bool) {
+			for _, f := range pc.Functions {
+				for _, ua := range f.AllArguments() {
+					if !yield(ua) {
+						return
 					}
 				}
-			},
-				filter),
-			filter,
-		)
-		// Attach per-function docs.
-		for i, f := range fns {
-			if f.Documentation == "" {
-				if d := pc.Functions[f.name].Documentation; d != "" {
-					fns[i].Documentation = d
-				} else if logger.Enabled(ctx, slog.LevelDebug) {
-					logger.Warn("no documentation", "for", f.Name(), "have", slices.Collect(maps.Keys(pc.Functions)))
-				}
+			}
+		},
+			filter),
+		filter,
+	)
+	// Attach per-function docs.
+	for i, f := range fns {
+		if f.Documentation == "" {
+			if d := pc.Functions[f.baseName()].Documentation; d != "" {
+				fns[i].Documentation = d
+			} else if d = pc.Functions[f.name].Documentation; d != "" {
+				fns[i].Documentation = d
+			} else if logger.Enabled(ctx, slog.LevelDebug) {
+				logger.Warn("no documentation", "for", f.Name(), "have", slices.Collect(maps.Keys(pc.Functions)))
 			}
 		}
-		functions = append(functions, fns...)
-		logger.Debug("parse", "pkg", pkgName, "functions", functions)
 	}
-	return packages, functions, annotations, nil
+	return fns
 }
@@ -0,0 +1,117 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestExpandCursors(t *testing.T) {
+	base := UserArgument{PackageName: "PKG", ObjectName: "GET_ROWS", ObjectID: 1, SubprogramID: 1}
+	id, cur := base, base
+	id.ArgumentName, id.InOut, id.DataType, id.PlsType = "P_ID", "IN", "NUMBER", "NUMBER"
+	cur.ArgumentName, cur.InOut, cur.DataType, cur.PlsType = "P_CUR", "OUT", "REF CURSOR", "REF CURSOR"
+	pc := PackageCache{Name: "PKG", Functions: map[string]FunctionCache{
+		"GET_ROWS": {Name: "GET_ROWS", Arguments: []UserArgument{id, cur},
+			Cursors: map[string][]UserArgument{"P_CUR": {
+				{ArgumentName: "ID", DataType: "NUMBER", PlsType: "NUMBER", DataPrecision: 9},
+				{ArgumentName: "NAME", DataType: "VARCHAR2", PlsType: "VARCHAR2", CharLength: 30},
+			}},
+		},
+	}}
+
+	if uas := pc.Functions["GET_ROWS"].AllArguments(); len(uas) != 5 {
+		t.Fatalf("got %d arguments, wanted 5: %+v", len(uas), uas)
+	} else if again := ExpandCursors(uas, pc.Functions["GET_ROWS"].Cursors); len(again) != len(uas) {
+		t.Errorf("strongly typed cursor expanded: %+v", again)
+	}
+
+	fns := ParsePackageCache(context.Background(), pc, nil)
+	if len(fns) != 1 {
+		t.Fatalf("got %d functions", len(fns))
+	}
+	f := fns[0]
+	if !f.HasCursorOut() {
+		t.Fatal("no cursor")
+	}
+	if a := f.Args[1]; a.TableOf == nil || len(a.TableOf.RecordOf) != 2 || a.TableOf.RecordOf[1].Name != "name" {
+		t.Fatalf("cursor columns: %+v", a.TableOf)
+	}
+	var buf bytes.Buffer
+	if err := f.SaveProtobuf(&buf, make(map[string]struct{})); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, "repeated ") || !strings.Contains(s, "string name = 2;") {
+		t.Errorf("got %s", s)
+	}
+}
+
+func TestAppendResults(t *testing.T) {
+	id := UserArgument{PackageName: "PKG", ObjectName: "LIST", ObjectID: 1, SubprogramID: 2,
+		ArgumentName: "P_ID", InOut: "IN", DataType: "NUMBER", PlsType: "NUMBER"}
+	pc := PackageCache{Name: "PKG", Functions: map[string]FunctionCache{
+		"LIST": {Name: "LIST", Arguments: []UserArgument{id},
+			Results: []ResultCache{
+				{Name: "emps", Columns: []UserArgument{
+					{ArgumentName: "ID", DataType: "NUMBER", PlsType: "NUMBER", DataPrecision: 9},
+				}},
+				{Name: "depts", Columns: []UserArgument{
+					{ArgumentName: "NAME", DataType: "VARCHAR2", PlsType: "VARCHAR2", CharLength: 30},
+				}},
+			},
+		},
+	}}
+
+	fns := ParsePackageCache(context.Background(), pc, nil)
+	if len(fns) != 1 {
+		t.Fatalf("got %d functions", len(fns))
+	}
+	f := fns[0]
+	if len(f.Args) != 1 || len(f.Results) != 2 || f.Results[1].Name != "depts" {
+		t.Fatalf("args=%+v results=%+v", f.Args, f.Results)
+	}
+	if !f.HasCursorOut() {
+		t.Fatal("results are not streamed")
+	}
+	var buf bytes.Buffer
+	if err := f.SaveProtobuf(&buf, make(map[string]struct{})); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, "emps = ") || !strings.Contains(s, "depts = ") {
+		t.Errorf("got %s", s)
+	}
+	if plsql, callFun := f.PlsqlBlock(""); strings.Contains(plsql, "emps") || !strings.Contains(callFun, "NextResultSet") {
+		t.Errorf("got %s\n%s", plsql, callFun)
+	}
+}
+
+func TestResultsWithOut(t *testing.T) {
+	id := UserArgument{PackageName: "PKG", ObjectName: "LIST", ObjectID: 1, SubprogramID: 2,
+		ArgumentName: "P_ID", InOut: "IN", DataType: "NUMBER", PlsType: "NUMBER"}
+	cnt := id
+	cnt.ArgumentName, cnt.InOut = "P_COUNT", "OUT"
+	pc := PackageCache{Name: "PKG", Functions: map[string]FunctionCache{
+		"LIST": {Name: "LIST", Arguments: []UserArgument{id, cnt},
+			Results: []ResultCache{{Name: "emps", Columns: []UserArgument{
+				{ArgumentName: "ID", DataType: "NUMBER", PlsType: "NUMBER", DataPrecision: 9},
+			}}},
+		},
+	}}
+
+	functions := ParsePackageCache(context.Background(), pc, nil)
+	if len(functions) != 1 || len(functions[0].Results) != 1 {
+		t.Fatalf("got %+v", functions)
+	}
+	var buf bytes.Buffer
+	if err := SaveProtobuf(t.Context(), &buf, functions, "pkg", ""); err == nil || !strings.Contains(strings.ToLower(err.Error()), "list: implicit results") {
+		t.Errorf("SaveProtobuf: got %v", err)
+	}
+	if err := SaveFunctions(t.Context(), &buf, functions, "pkg", "", false); err == nil || !strings.Contains(strings.ToLower(err.Error()), "list: implicit results") {
+		t.Errorf("SaveFunctions: got %v", err)
+	}
+}
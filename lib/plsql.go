@@ -13,9 +13,11 @@ import (
 	"go/format"
 	"io"
 	"os"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/UNO-SOFT/zlog/v2/slog"
@@ -26,16 +28,19 @@ import (
 // MaxTableSize is the default size of the array elements
 var MaxTableSize = 128
 
+// LobChunkSize is the default chunk size of the streamed LOB outputs.
+var LobChunkSize = 1 << 20
+
 const batchSize = 1024
 
 // SavePlsqlBlock saves the plsql block definition into writer
 func (fun Function) PlsqlBlock(checkName string) (plsql, callFun string) {
-	decls, pre, call, post, convIn, convOut, err := fun.prepareCall()
+	decls, pre, call, callList, post, convIn, convOut, err := fun.prepareCall()
 	if err != nil {
 		// logger.Error("error preparing", "function", fun, "error", err)
 		panic(fmt.Errorf("%s: %w", fun.Name(), err))
 	}
-	fn := fun.name
+	fn := fun.baseName()
 	if fun.alias != "" {
 		fn = fun.alias
 	}
@@ -44,32 +49,7 @@ func (fun Function) PlsqlBlock(checkName string) (plsql, callFun string) {
 	plsBuf := Buffers.Get()
 	defer Buffers.Put(plsBuf)
 	plsBuf.Reset()
-	if len(decls) > 0 {
-		io.WriteString(plsBuf, "DECLARE\n")
-		for _, line := range decls {
-			fmt.Fprintf(plsBuf, "  %s\n", line)
-		}
-		plsBuf.Write([]byte{'\n'})
-	}
-	io.WriteString(plsBuf, "BEGIN\n")
-	for _, line := range pre {
-		fmt.Fprintf(plsBuf, "  %s\n", line)
-	}
-	if len(fun.handle) == 0 {
-		plsBuf.WriteString("\n")
-	} else {
-		plsBuf.WriteString("  BEGIN\n  ")
-	}
-	fmt.Fprintf(plsBuf, "  %s;\n", call)
-	if len(fun.handle) != 0 {
-		fmt.Fprintf(plsBuf, "  EXCEPTION WHEN %s THEN NULL;\n  END;\n",
-			strings.Join(fun.handle, " OR "))
-	}
-	plsBuf.WriteByte('\n')
-	for _, line := range post {
-		fmt.Fprintf(plsBuf, "  %s\n", line)
-	}
-	io.WriteString(plsBuf, "\nEND;\n")
+	fun.writePlsql(plsBuf, decls, pre, call, post)
 
 	var check string
 	if checkName != "" {
@@ -97,13 +77,34 @@ func (fun Function) PlsqlBlock(checkName string) (plsql, callFun string) {
 			CamelCase(fun.getStructName(true, false)),
 		)
 	} else {
+		name := CamelCase(fn)
+		if fun.Retryable() {
+			// each attempt is a whole transaction - but not in a joined one
+			fmt.Fprintf(callBuf, `func (s *oracallServer) %s(ctx context.Context, input *pb.%s) (output *pb.%s, err error) {
+	if oracall.JoinsTx(ctx) {
+		return s.call%s(ctx, input)
+	}
+	err = s.RetryPolicy.Do(ctx, %d, func(ctx context.Context) error {
+		var err error
+		output, err = s.call%s(ctx, input)
+		return err
+	})
+	return
+}
+
+`,
+				name, CamelCase(fun.getStructName(false, false)), CamelCase(fun.getStructName(true, false)),
+				name, fun.MaxAttempts, name,
+			)
+			name = "call" + name
+		}
 		fmt.Fprintf(callBuf, `func (s *oracallServer) %s(ctx context.Context, input *pb.%s) (output *pb.%s, err error) {
 		%s
 		output = new(pb.%s)
 		iterators := make([]iterator, 0, 1) // just temporary
 		_ = iterators
     `,
-			CamelCase(fn), CamelCase(fun.getStructName(false, false)), CamelCase(fun.getStructName(true, false)),
+			name, CamelCase(fun.getStructName(false, false)), CamelCase(fun.getStructName(true, false)),
 			check,
 			CamelCase(fun.getStructName(true, false)),
 		)
@@ -115,9 +116,11 @@ func (fun Function) PlsqlBlock(checkName string) (plsql, callFun string) {
 	}
 	if err = ctx.Err(); err != nil { return }
 	const funName = "%s"
+	ctx, call := oracall.StartCall(ctx, funName, %q)
+	defer func() { call.End(err) }()
 	if s.BeforeHook != nil { if err = s.BeforeHook(ctx, funName, input); err != nil { return }}
 	`,
-		fun.Name())
+		fun.Name(), fun.lastDDL())
 	for _, line := range convIn {
 		io.WriteString(callBuf, line+"\n")
 	}
@@ -144,13 +147,23 @@ func (fun Function) PlsqlBlock(checkName string) (plsql, callFun string) {
 		slog.Info("not found", "name", fun.RealName(), "in", call)
 	}
 	j := i + strings.Index(call[i:], ")") + 1
+	withCancel := "context.WithCancel(ctx)"
+	if fun.Timeout > 0 {
+		withCancel = fmt.Sprintf("context.WithTimeout(ctx, %d) // %s", fun.Timeout, fun.Timeout)
+	}
+	txMode, commit := fun.TxMode(), "call.Commit(tx)"
+	if !txMode.Commits() {
+		commit = "call.Rollback(tx)"
+	}
 	fmt.Fprintf(callBuf, `
-	ctx, cancel := context.WithCancel(ctx)
+	ctx, cancel := `+withCancel+`
 	defer cancel()
-	var tx *sql.Tx
-	if tx, err = s.db.BeginTx(ctx, nil); err != nil {
+	var tx oracall.Tx
+	var endTx func()
+	if tx, endTx, err = s.Transactions.BeginTx(ctx, s.db, `+txMode.goString()+`); err != nil {
 		return
 	}
+	defer endTx()
 	defer tx.Rollback()
 	ctx = godror.ContextWithTraceTag(ctx, godror.TraceTag{Module: %q, Action: %q})
 const callText = `+"`%s`"+`
@@ -163,6 +176,22 @@ if DebugLevel > 0 {
 		call[i:j], rIdentifier.ReplaceAllString(pls, "'%#v'"),
 		fun.getPlsqlConstName(),
 	)
+	if optional := fun.optionalArgs(); len(optional) != 0 && callList != nil {
+		callBuf.WriteString("\tvar omit []string\n")
+		for _, arg := range optional {
+			fmt.Fprintf(callBuf, "\tif input.%s == nil { omit = append(omit, %q) }\n", CamelCase(arg.Name), arg.Name)
+		}
+		callBuf.WriteString("\tif len(omit) != 0 {\n\t\tqry = oracall.OmitCallArgs(qry, ")
+		// "{ {", as callBuf is a template - gofmt joins them
+		fmt.Fprintf(callBuf, "%q, []oracall.CallArg{ ", fun.RealName())
+		for i, a := range positionalCallArgs(plsBuf.String(), fun.RealName(), callList) {
+			if i > 0 {
+				callBuf.WriteString(", ")
+			}
+			fmt.Fprintf(callBuf, "{Name: %q, Value: %q}", a.Name, a.Value)
+		}
+		callBuf.WriteString("}, omit...)\n\t}\n")
+	}
 	aS := "1024"
 	if fun.maxTableSize > 0 {
 		if fun.maxTableSize < 1<<16 {
@@ -171,18 +200,28 @@ if DebugLevel > 0 {
 			aS = "65536"
 		}
 	}
+	// the --oracall:secret arguments are redacted in the logs, and the bind values are left out of the errors
+	logInput, logOutput, errParams := "input", "output", "params"
 	var hasPassword bool
-	for _, arg := range fun.Args {
-		if !hasPassword && arg.IsInput() {
-			if hasPassword = strings.HasSuffix(strings.TrimPrefix(strings.ToLower(arg.Name), "p_"), "jelszo"); hasPassword {
-				break
+	if fun.hasSecret() {
+		logInput, logOutput, errParams = "oracall.Redact(input)", "oracall.Redact(output)", "funName"
+	} else {
+		// without annotations, the input is not logged if it has a password
+		for _, arg := range fun.Args {
+			if arg.IsInput() {
+				if hasPassword = strings.HasSuffix(strings.TrimPrefix(strings.ToLower(arg.Name), "p_"), "jelszo"); hasPassword {
+					errParams = "funName"
+					break
+				}
 			}
 		}
 	}
 
 	callBuf.WriteString(`
 	if s.PrepareHook != nil { if err = s.PrepareHook(ctx, funName, &qry, &params); err != nil { return } }
+	endPrepare := call.Phase("prepare")
 	stmt, stmtErr := tx.PrepareContext(ctx, qry)
+	endPrepare(stmtErr)
 	if stmtErr != nil {
 		err = fmt.Errorf("%s: %w", qry, stmtErr)
 		return
@@ -192,61 +231,102 @@ if DebugLevel > 0 {
 	dl, _ := ctx.Deadline()
 	if s.DBLog != nil {
 		var err error
-		if ctx, err = s.DBLog(ctx, tx, funName, input); err != nil {
+		if ctx, err = s.DBLog(ctx, tx, funName, ` + logInput + `); err != nil {
 			logger.Error("dbLog", "fun", funName, "error", err)
 		}
 	}
+	var readDbmsOutput func(context.Context) ([]string, error)
+	if readDbmsOutput, err = oracall.EnableDbmsOutput(ctx, tx, s.DbmsOutput); err != nil {
+		return
+	}
 	logger.Info( "calling", "fun", funName, `)
 	if !hasPassword {
-		callBuf.WriteString(`"input", input, `)
+		callBuf.WriteString(`"input", ` + logInput + `, `)
+	}
+	// godror returns the implicit result sets only from a query
+	exec := "_, err = stmt.ExecContext("
+	if len(fun.Results) != 0 {
+		exec = "rset, err = stmt.QueryContext("
 	}
 	callBuf.WriteString(`"stmt", stmtP, "deadline", dl.UTC().Format(time.RFC3339))
-	_, err = stmt.ExecContext(ctx, append(params, godror.PlSQLArrays, godror.ArraySize(`)
+	`)
+	if len(fun.Results) != 0 {
+		callBuf.WriteString("var rset *sql.Rows\n")
+	}
+	callBuf.WriteString("endExec := call.Phase(\"exec\")\n")
+	callBuf.WriteString(exec + `ctx, append(params, godror.PlSQLArrays, godror.ArraySize(`)
 	callBuf.WriteString(aS)
 	callBuf.WriteString(`))...)
 	logger.Info( "finished", "fun", funName, "stmt", stmtP, "error", err)
+	endExec(err)
+	if c, ok := err.(interface{ Code() int }); ok && c.Code() == 4068 {
+		// "existing state of packages has been discarded"
+		` + exec + `ctx, append(params, godror.PlSQLArrays, godror.ArraySize(`)
+	callBuf.WriteString(aS)
+	callBuf.WriteString(`))...)
+	}
+	// read (and disable) the DBMS_OUTPUT on the error paths, too, before the session goes back to the pool
+	if readDbmsOutput != nil {
+		if lines, outErr := readDbmsOutput(ctx); outErr != nil {
+			logger.Warn("dbms_output", "fun", funName, "stmt", stmtP, "error", outErr)
+		} else {
+			logger.Debug("dbms_output", "fun", funName, "stmt", stmtP, "lines", lines)
+		}
+	}
 	if err != nil {
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 			return
 		}
-		if c, ok := err.(interface{ Code() int }); ok && c.Code() == 4068 {
-			// "existing state of packages has been discarded"
-			_, err = stmt.ExecContext(ctx, append(params, godror.PlSQLArrays, godror.ArraySize(`)
-	callBuf.WriteString(aS)
-	callBuf.WriteString(`))...)
-		}
-		if err != nil {
-			qe := oracall.NewQueryError(qry, fmt.Errorf("%v: %w", params, err))
-			err = qe
-			if s.DBLog != nil {
-				var logErr error
-				if _, logErr = s.DBLog(ctx, tx, funName, err); logErr != nil {
-					logger.Error("dbLog", "fun", funName, "error", logErr)
-				}
-			}
-			if qe.Code() == 6502 {  // Numeric or Value Error
-				err = fmt.Errorf("%+v: %w", qe, oracall.ErrInvalidArgument)
+		qe := oracall.NewQueryError(qry, fmt.Errorf("%v: %w", ` + errParams + `, err))
+		err = qe
+		if s.DBLog != nil {
+			var logErr error
+			if _, logErr = s.DBLog(ctx, tx, funName, err); logErr != nil {
+				logger.Error("dbLog", "fun", funName, "error", logErr)
 			}
-			return
 		}
+		if qe.Code() == 6502 {  // Numeric or Value Error
+			err = fmt.Errorf("%+v: %w", qe, oracall.ErrInvalidArgument)
+		}
+		return
 	}
     `)
 
-	callBuf.WriteString("\nif DebugLevel > 0 { logger.Debug(`result params`, params, `output`, output) }\n")
+	if errParams == "params" {
+		callBuf.WriteString("\nif DebugLevel > 0 { logger.Debug(`result params`, params, `output`, output) }\n")
+	} else {
+		// the bind values hold the secrets, too
+		callBuf.WriteString("\nif DebugLevel > 0 { logger.Debug(`result`, `output`, " + logOutput + ") }\n")
+	}
 	for _, line := range convOut {
 		io.WriteString(callBuf, line+"\n")
 	}
+	if len(fun.Results) != 0 {
+		fun.writeResultsIterator(callBuf)
+	}
 	callBuf.WriteString("\nif s.AfterHook != nil { if err = s.AfterHook(ctx, funName, params, output); err != nil { return }}\n")
 	if !hasCursorOut {
-		fmt.Fprintf(callBuf, "\nerr = tx.Commit()\nreturn\n")
+		fmt.Fprintf(callBuf, "\nerr = %s\nreturn\n", commit)
 	} else {
 		fmt.Fprintf(callBuf, `
 		if len(iterators) == 0 {
 			if err = stream.Send(output); err == nil {
-				err = tx.Commit()
+				err = %s
 			}
 			return
+		}`, commit)
+		if fun.hasStreamedLob() {
+			callBuf.WriteString(`
+		// the first message carries the scalar outputs, the next ones the chunks
+		if err = stream.Send(output); err != nil {
+			return
 		}
+		output.Reset()
+		`)
+		}
+		callBuf.WriteString(`
+		endIterate := call.Phase("iterate")
+		defer func() { endIterate(err) }()
 		iterators2 := make([]iterator, 0, len(iterators))
 		for {
 			for _, it := range iterators {
@@ -267,7 +347,8 @@ if DebugLevel > 0 {
 			}
 			if len(iterators) != len(iterators2) {
 				if len(iterators2) == 0 {
-					err = tx.Commit()
+					endIterate(nil)
+					err = ` + commit + `
 					return
 				}
 				iterators = append(iterators[:0], iterators2...)
@@ -277,6 +358,9 @@ if DebugLevel > 0 {
 		`)
 	}
 	callBuf.WriteString("\n}\n")
+	if uploads := fun.uploads(); len(uploads) != 0 {
+		fun.writeUpload(callBuf, fn, uploads)
+	}
 	callFun = callBuf.String()
 	plsql = plsBuf.String()
 
@@ -284,6 +368,207 @@ if DebugLevel > 0 {
 	return
 }
 
+// writeUpload writes the client-streaming variant of the call, which receives the LOB inputs in chunks,
+// spilling them into temp files, then calls the function with readers of them.
+func (fun Function) writeUpload(w io.Writer, fn string, uploads []Argument) {
+	inName, outName := CamelCase(fun.getStructName(false, false)), CamelCase(fun.getStructName(true, false))
+	fmt.Fprintf(w, `
+// %sUpload receives the LOBs in a client stream, spilled into temp files, then calls %s.
+func (s *oracallServer) %sUpload(stream pb.%s_%sUploadServer) (err error) {
+	var input *pb.%s
+	`, CamelCase(fn), CamelCase(fn), CamelCase(fn), CamelCase(fun.Package), CamelCase(fn), inName)
+	for _, arg := range uploads {
+		fmt.Fprintf(w, "lob%s := custom.NewSpill(1<<20)\ndefer lob%s.Close()\n", CamelCase(arg.Name), CamelCase(arg.Name))
+	}
+	io.WriteString(w, `for {
+		msg, recvErr := stream.Recv()
+		if recvErr != nil {
+			if errors.Is(recvErr, io.EOF) {
+				break
+			}
+			return recvErr
+		}
+	`)
+	for _, arg := range uploads {
+		name := CamelCase(arg.Name)
+		if arg.Type == "CLOB" {
+			fmt.Fprintf(w, "if _, err = io.WriteString(lob%s, msg.%s); err != nil {\nreturn\n}\nmsg.%s = \"\"\n", name, name, name)
+		} else {
+			fmt.Fprintf(w, "if _, err = lob%s.Write(msg.%s); err != nil {\nreturn\n}\nmsg.%s = nil\n", name, name, name)
+		}
+	}
+	fmt.Fprintf(w, `if input == nil {
+			input = msg
+		}
+	}
+	if input == nil {
+		input = new(pb.%s)
+	}
+	readers := make(map[string]io.Reader, %d)
+	`, inName, len(uploads))
+	for _, arg := range uploads {
+		fmt.Fprintf(w, "if readers[%q], err = lob%s.Reader(); err != nil {\nreturn\n}\n", arg.Name, CamelCase(arg.Name))
+	}
+	fmt.Fprintf(w, `var output *pb.%s
+	if output, err = s.%s(oracall.WithLobReaders(stream.Context(), readers), input); err != nil {
+		return
+	}
+	return stream.SendAndClose(output)
+}
+`, outName, CamelCase(fn))
+}
+
+// optionalArgs returns the arguments which are left out of the call when not set.
+func (fun Function) optionalArgs() []Argument {
+	var args []Argument
+	for _, arg := range fun.Args {
+		if arg.IsOptional() {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+// OmitCallArgs returns the PL/SQL block with the named arguments left out of the call of fun,
+// to let their DEFAULT values apply.
+//
+// args are the arguments of the call in the block, as PlsqlCall returns them:
+// the call is rebuilt from the kept ones.
+func OmitCallArgs(plsql, fun string, args []CallArg, names ...string) string {
+	kept := slices.DeleteFunc(slices.Clone(args), func(a CallArg) bool { return slices.Contains(names, a.Name) })
+	return strings.Replace(plsql, callText(fun, args), callText(fun, kept), 1)
+}
+
+// Bind is a bind variable of the PL/SQL block returned by PlsqlCall.
+type Bind struct {
+	// Arg is the function's argument (or the return value, named "ret").
+	Arg *Argument
+	// Name is the name of the bind variable in the PL/SQL block.
+	Name string
+	// Field is the record field's name, for record and table of records arguments.
+	Field string
+	// Key is true for the keys of a map (INDEX BY VARCHAR2) argument.
+	Key bool
+}
+
+var varNamesMu sync.Mutex
+
+// PlsqlCall returns the PL/SQL block calling the function, with positional (:1, :2, ...)
+// placeholders, the bind variable for each placeholder, in order,
+// and the arguments of the function call in it, for OmitCallArgs.
+// A bind variable may appear more than once (IN OUT tables).
+//
+// This is what PlsqlBlock generates, for calling the function without generated code.
+func (fun Function) PlsqlCall() (plsql string, binds []Bind, callArgs []CallArg, err error) {
+	if fun.Replacement != nil {
+		return "", nil, nil, fmt.Errorf("%s: replaced functions: %w", fun.Name(), ErrUnsupported)
+	}
+	varNamesMu.Lock()
+	defer varNamesMu.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			plsql, binds, callArgs, err = "", nil, nil, fmt.Errorf("%s: %v: %w", fun.Name(), r, ErrUnsupported)
+		}
+	}()
+	decls, pre, call, callList, post, _, _, err := fun.prepareCall()
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("%s: %w", fun.Name(), err)
+	}
+
+	// the same names as prepareCall uses
+	byName := make(map[string]Bind)
+	args := make([]Argument, 0, len(fun.Args)+1)
+	for _, arg := range fun.Args {
+		arg.Name = replHidden(arg.Name)
+		args = append(args, arg)
+	}
+	if fun.Returns != nil {
+		args = append(args, *fun.Returns)
+	}
+	for i := range args {
+		arg := &args[i]
+		if arg.IsMap() {
+			nm := getParamName(fun.Name(), arg.Name+".key")
+			byName[nm] = Bind{Arg: arg, Name: nm, Key: true}
+		}
+		var vn string
+		switch {
+		case arg.Flavor == FLAVOR_SIMPLE, arg.Type == "REF CURSOR",
+			arg.Flavor == FLAVOR_TABLE && arg.TableOf.Flavor == FLAVOR_SIMPLE:
+			nm := arg.Name
+			if strings.HasSuffix(nm, MarkHidden) {
+				nm = nm[:len(nm)-len(MarkHidden)] + "#"
+			}
+			byName[nm] = Bind{Arg: arg, Name: nm}
+			continue
+		case arg.Flavor == FLAVOR_RECORD:
+			vn = getInnerVarName(fun.Name(), arg.Name)
+			for _, a := range arg.RecordOf {
+				if a.Flavor != FLAVOR_SIMPLE {
+					return "", nil, nil, fmt.Errorf("%s: %s.%s is not simple: %w", fun.Name(), arg.Name, a.Name, ErrUnsupported)
+				}
+			}
+		case arg.Flavor == FLAVOR_TABLE && arg.TableOf.Flavor == FLAVOR_RECORD:
+			vn = getInnerVarName(fun.Name(), arg.Name+"."+arg.TableOf.Name)
+		default:
+			return "", nil, nil, fmt.Errorf("%s: %s: %w", fun.Name(), arg.Name, ErrUnsupported)
+		}
+		fields := arg.RecordOf
+		if arg.TableOf != nil {
+			fields = arg.TableOf.RecordOf
+		}
+		for _, a := range fields {
+			nm := getParamName(fun.Name(), vn+"."+a.Name)
+			byName[nm] = Bind{Arg: arg, Name: nm, Field: a.Name}
+		}
+	}
+
+	buf := Buffers.Get()
+	defer Buffers.Put(buf)
+	buf.Reset()
+	fun.writePlsql(buf, decls, pre, call, post)
+	callArgs = positionalCallArgs(buf.String(), fun.RealName(), callList)
+	plsql, _ = godror.MapToSlice(buf.String(), func(key string) any {
+		b, ok := byName[key]
+		if !ok && err == nil {
+			err = fmt.Errorf("%s: unknown bind variable %q: %w", fun.Name(), key, ErrUnsupported)
+		}
+		binds = append(binds, b)
+		return nil
+	})
+	return plsql, binds, callArgs, err
+}
+
+// writePlsql writes the PL/SQL block, with named bind variables.
+func (fun Function) writePlsql(plsBuf *bytes.Buffer, decls, pre []string, call string, post []string) {
+	if len(decls) > 0 {
+		io.WriteString(plsBuf, "DECLARE\n")
+		for _, line := range decls {
+			fmt.Fprintf(plsBuf, "  %s\n", line)
+		}
+		plsBuf.Write([]byte{'\n'})
+	}
+	io.WriteString(plsBuf, "BEGIN\n")
+	for _, line := range pre {
+		fmt.Fprintf(plsBuf, "  %s\n", line)
+	}
+	if len(fun.handle) == 0 {
+		plsBuf.WriteString("\n")
+	} else {
+		plsBuf.WriteString("  BEGIN\n  ")
+	}
+	fmt.Fprintf(plsBuf, "  %s;\n", call)
+	if len(fun.handle) != 0 {
+		fmt.Fprintf(plsBuf, "  EXCEPTION WHEN %s THEN NULL;\n  END;\n",
+			strings.Join(fun.handle, " OR "))
+	}
+	plsBuf.WriteByte('\n')
+	for _, line := range post {
+		fmt.Fprintf(plsBuf, "  %s\n", line)
+	}
+	io.WriteString(plsBuf, "\nEND;\n")
+}
+
 func demap(plsql, callFun string) (string, string) {
 	var i int
 	paramsMap := make(map[string][]int, 16)
@@ -398,7 +683,7 @@ func demap(plsql, callFun string) (string, string) {
 	return plsql, callBuf.String()
 }
 
-func (fun Function) prepareCall() (decls, pre []string, call string, post []string, convIn, convOut []string, err error) {
+func (fun Function) prepareCall() (decls, pre []string, call string, callList []CallArg, post []string, convIn, convOut []string, err error) {
 	callArgs := make(map[string]string, 16)
 	if repl := fun.Replacement; repl != nil {
 		decls = append(decls, "v_in CLOB := :1;")
@@ -437,7 +722,7 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 			}
 			call = fmt.Sprintf("%s(%s=>v_in, %s=>:2)", repl.RealName(), argIn.Name, argOut.Name)
 		}
-		return decls, pre, call, post, convIn, convOut, nil
+		return decls, pre, call, nil, post, convIn, convOut, nil
 	}
 
 	tableTypes := make(map[string]string, 4)
@@ -481,6 +766,7 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 	var (
 		vn, tmp, typ string
 		ok           bool
+		hasKeyVar    bool
 	)
 	decls = append(decls, "i1 PLS_INTEGER;", "i2 PLS_INTEGER;")
 	convIn = append(convIn,
@@ -502,6 +788,12 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 		case FLAVOR_SIMPLE:
 			name := (CamelCase(arg.Name))
 			//name := capitalize(replHidden(arg.Name))
+			if arg.IsOptional() {
+				// bind into a variable, so the argument can be omitted from the call
+				vn = getInnerVarName(fun.Name(), arg.Name)
+				decls = append(decls, vn+" "+strings.TrimPrefix(arg.AbsType, "PL/SQL ")+" := :"+arg.Name+"; --O="+arg.Name)
+				callArgs[arg.Name] = vn
+			}
 			convIn, convOut = arg.getConvSimple(convIn, convOut,
 				name, addParam(arg.Name))
 
@@ -577,6 +869,19 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 				convIn, convOut = arg.getConvSimpleTable(convIn, convOut,
 					name, addParam(arg.Name), maxTableSize)
 			} else {
+				var keys string // the keys of the map, in a parallel array
+				if arg.IsMap() {
+					if !hasKeyVar {
+						decls = append(decls, "s1 VARCHAR2(32767);")
+						hasKeyVar = true
+					}
+					keys = getParamName(fun.Name(), arg.Name+".key")
+					setvar := ""
+					if arg.IsInput() {
+						setvar = " := :" + keys
+					}
+					decls = append(decls, keys+" "+getTableType("VARCHAR2(32767)")+setvar+"; --K="+arg.Name)
+				}
 				switch arg.TableOf.Flavor {
 				case FLAVOR_SIMPLE: // like simple, but for the arg.TableOf
 					typ = getTableType(arg.TableOf.AbsType)
@@ -598,6 +903,31 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 					} else {
 						decls = append(decls, vn+" "+arg.TypeName+"; --B="+arg.Name)
 					}
+					if keys != "" {
+						if arg.IsInput() {
+							pre = append(pre,
+								vn+".DELETE;",
+								"i1 := "+keys+".FIRST;",
+								"WHILE i1 IS NOT NULL LOOP",
+								"  "+vn+"("+keys+"(i1)) := "+arg.Name+"(i1);",
+								"  i1 := "+keys+".NEXT(i1);",
+								"END LOOP;")
+						}
+						if arg.IsOutput() {
+							post = append(post,
+								keys+".DELETE; "+arg.Name+".DELETE;",
+								"s1 := "+vn+".FIRST; i1 := 1;",
+								"WHILE s1 IS NOT NULL LOOP",
+								"  "+keys+"(i1) := s1; "+arg.Name+"(i1) := "+vn+"(s1);",
+								"  s1 := "+vn+".NEXT(s1); i1 := i1 + 1;",
+								"END LOOP;",
+								":"+keys+" := "+keys+";",
+								":"+arg.Name+" := "+arg.Name+";")
+						}
+						convIn, convOut = arg.getConvMap(convIn, convOut,
+							CamelCase(arg.Name), addParam(keys), addParam(arg.Name), maxTableSize)
+						break
+					}
 					if arg.IsInput() {
 						pre = append(pre,
 							vn+".DELETE;",
@@ -633,7 +963,10 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 
 					aname := (CamelCase(arg.Name))
 					//aname := capitalize(replHidden(arg.Name))
-					if arg.IsOutput() {
+					if keys != "" {
+						convIn, convOut = arg.getConvMapKeys(convIn, convOut,
+							aname, addParam(keys), maxTableSize)
+					} else if arg.IsOutput() {
 						var tgot string
 						if tgot, err = arg.TableOf.goType(true); err != nil {
 							return
@@ -677,6 +1010,23 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 
 					// here comes the loops
 					var idxvar string
+					// the index of vn in the input loop, and the loop variable of the output loop
+					inIdx, outVar := "i1", "i1"
+					if keys != "" {
+						idxvar, inIdx, outVar = keys, keys+"(i1)", "s1"
+						if arg.IsInput() {
+							pre = append(pre, "",
+								"i1 := "+idxvar+".FIRST;",
+								"WHILE i1 IS NOT NULL LOOP")
+						}
+						if arg.IsOutput() {
+							post = append(post, "",
+								keys+".DELETE;",
+								"s1 := "+vn+".FIRST; i2 := 1;",
+								"WHILE s1 IS NOT NULL LOOP",
+								"  "+keys+"(i2) := s1;")
+						}
+					}
 					for _, a := range arg.TableOf.RecordOf {
 						k, v := a.Name, a.Argument
 
@@ -704,7 +1054,8 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 							[2]string{aname, kName},
 							addParam(tmp),
 							uint(maxTableSize),
-							*arg.TableOf)
+							*arg.TableOf,
+							keys != "")
 
 						if arg.IsInput() {
 							if arg.IsNestedTable() {
@@ -712,11 +1063,11 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 									"  "+vn+".extend;")
 							}
 							pre = append(pre,
-								"  "+vn+"(i1)."+k+" := "+tmp+"(i1);")
+								"  "+vn+"("+inIdx+")."+k+" := "+tmp+"(i1);")
 						}
 						if arg.IsOutput() {
 							post = append(post,
-								"  "+tmp+"(i2) := "+vn+"(i1)."+k+";")
+								"  "+tmp+"(i2) := "+vn+"("+outVar+")."+k+";")
 						}
 					}
 					if arg.IsInput() {
@@ -726,8 +1077,11 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 					}
 					if arg.IsOutput() {
 						post = append(post,
-							"  i1 := "+vn+".NEXT(i1); i2 := i2 + 1;",
+							"  "+outVar+" := "+vn+".NEXT("+outVar+"); i2 := i2 + 1;",
 							"END LOOP;")
+						if keys != "" {
+							post = append(post, ":"+keys+" := "+keys+";")
+						}
 						for _, a := range arg.TableOf.RecordOf {
 							k := a.Name
 							tmp = getParamName(fun.Name(), vn+"."+k)
@@ -743,33 +1097,91 @@ func (fun Function) prepareCall() (decls, pre []string, call string, post []stri
 		}
 	}
 
-	callb := Buffers.Get()
-	defer Buffers.Put(callb)
+	callList = make([]CallArg, 0, len(fun.Args))
+	for _, arg := range fun.Args {
+		if vn, ok = callArgs[arg.Name]; !ok {
+			vn = ":" + arg.Name
+		}
+		callList = append(callList, CallArg{Name: arg.Name, Value: vn})
+	}
+	call = callText(fun.RealName(), callList)
 	if fun.Returns != nil {
-		callb.WriteString(":ret := ")
+		call = ":ret := " + call
+	}
+	return
+}
+
+// CallArg is an argument of the function call in a PL/SQL block: Name=>Value.
+type CallArg struct{ Name, Value string }
+
+// callText returns the call of fun with the arguments, in named notation.
+func callText(fun string, args []CallArg) string {
+	if len(args) == 0 {
+		return fun
 	}
-	callb.WriteString(fun.RealName())
-	callb.WriteString("(")
-	for i, arg := range fun.Args {
+	var buf strings.Builder
+	buf.WriteString(fun)
+	buf.WriteByte('(')
+	for i, a := range args {
 		if i > 0 {
-			callb.WriteString(",\n\t\t")
+			buf.WriteString(",\n\t\t")
 		}
-		if vn, ok = callArgs[arg.Name]; !ok {
-			vn = ":" + arg.Name
+		buf.WriteString(a.Name)
+		buf.WriteString("=>")
+		buf.WriteString(a.Value)
+	}
+	buf.WriteByte(')')
+	return buf.String()
+}
+
+// positionalCallArgs returns the arguments of the call of fun in the PL/SQL block (with named bind variables),
+// with the positional placeholders godror.MapToSlice replaces them with.
+func positionalCallArgs(plsql, fun string, args []CallArg) []CallArg {
+	i := strings.Index(plsql, callText(fun, args))
+	if i < 0 {
+		return nil
+	}
+	mapped := func(s string) string {
+		s, _ = godror.MapToSlice(s, nil)
+		return s
+	}
+	// the placeholders are numbered from left to right, so the block before the value numbers them as in the whole
+	text := plsql[:i] + fun + "("
+	pos := make([]CallArg, len(args))
+	for i, a := range args {
+		if i > 0 {
+			text += ",\n\t\t"
 		}
-		fmt.Fprintf(callb, "%s=>%s", arg.Name, vn)
+		text += a.Name + "=>"
+		before := mapped(text)
+		text += a.Value
+		pos[i] = CallArg{Name: a.Name, Value: mapped(text)[len(before):]}
 	}
-	callb.WriteString(")")
-	call = callb.String()
-	return
+	return pos
 }
 
 func (arg Argument) getConvSimple(
 	convIn, convOut []string,
 	name, paramName string,
 ) ([]string, []string) {
+	if arg.IsStreamed() {
+		return arg.getConvLobStream(convIn, convOut, name, paramName)
+	}
+	if arg.Upload {
+		src := "input." + name
+		if arg.Type == "BLOB" {
+			src = "string(" + src + ")"
+		}
+		convIn = append(convIn, fmt.Sprintf(`%s = godror.Lob{IsClob:%t, Reader:oracall.LobReader(ctx, %q, strings.NewReader(%s))} // gclu`,
+			paramName, arg.Type == "CLOB", arg.Name, src))
+		return convIn, convOut
+	}
 	if !arg.IsOutput() {
-		in, _ := arg.ToOra(paramName, "input."+name, arg.Direction)
+		src := "input." + name
+		if arg.IsOptional() {
+			src = "input.Get" + name + "()"
+		}
+		in, _ := arg.ToOra(paramName, src, arg.Direction)
 		convIn = append(convIn, in+"  // gcs4i")
 	} else {
 		got, err := arg.goType(false)
@@ -799,6 +1211,43 @@ func (arg Argument) getConvSimple(
 	return convIn, convOut
 }
 
+// getConvLobStream binds the LOB output into a godror.Lob, and adds an iterator reading it in chunks.
+func (arg Argument) getConvLobStream(
+	convIn, convOut []string,
+	name, paramName string,
+) ([]string, []string) {
+	isClob := arg.Type == "CLOB"
+	vn := mkVarName(paramName)
+	src, chunk, zero := "input."+name, "string(b)", `""`
+	if !isClob {
+		src, chunk, zero = "string(input."+name+")", "b", "nil"
+	}
+	if arg.IsInput() {
+		convIn = append(convIn, fmt.Sprintf(`%s := godror.Lob{IsClob:%t, Reader:strings.NewReader(%s)}; %s = sql.Out{Dest:&%s, In:true} // gcls`,
+			vn, isClob, src, paramName, vn))
+	} else {
+		convIn = append(convIn, fmt.Sprintf(`%s := godror.Lob{IsClob:%t}; %s = sql.Out{Dest:&%s} // gcls`,
+			vn, isClob, paramName, vn))
+	}
+	convOut = append(convOut, fmt.Sprintf(`
+	if %s.Reader != nil {
+		chunks := custom.NewChunks(%s.Reader, %d, %t)
+		iterators = append(iterators, iterator{
+			Reset: func() { output.%s = %s },
+			Iterate: func() error {
+				b, err := chunks.Next()
+				output.%s = %s
+				return err
+			},
+		})
+	}`,
+		vn, vn, arg.ChunkSize, isClob,
+		name, zero,
+		name, chunk,
+	))
+	return convIn, convOut
+}
+
 func (arg Argument) getConvSimpleTable(
 	convIn, convOut []string,
 	name, paramName string,
@@ -915,6 +1364,7 @@ func (arg Argument) getConvRefCursor(
 				a = append(a, %s)
 			}
 			output.%s = a
+			call.AddRows(len(a))
 			return err
 			},
 			})
@@ -931,6 +1381,67 @@ func (arg Argument) getConvRefCursor(
 	return convIn, convOut
 }
 
+// writeResultsIterator writes the iterator streaming the implicit result sets from rset, one after the other.
+func (fun Function) writeResultsIterator(w io.Writer) {
+	var cols int
+	reset := make([]string, len(fun.Results))
+	for i, arg := range fun.Results {
+		cols = max(cols, len(arg.TableOf.RecordOf))
+		reset[i] = fmt.Sprintf("output.%s = output.%s[:0]", CamelCase(arg.Name), CamelCase(arg.Name))
+	}
+	fmt.Fprintf(w, `
+	{
+		defer rset.Close()
+		I := make([]any, %d)
+		P := make([]any, len(I))
+		for i := range I {
+			P[i] = &I[i]
+		}
+		idx := -1 // the actual result set
+		iterators = append(iterators, iterator{
+			Reset: func() { %s },
+			Iterate: func() error {
+				for idx < %d {
+					if idx >= 0 {
+						var n int
+						for ; n < %d && rset.Next(); n++ {
+							switch idx {
+	`,
+		cols, strings.Join(reset, "; "), len(fun.Results), batchSize)
+	for i, arg := range fun.Results {
+		name := CamelCase(arg.Name)
+		fmt.Fprintf(w, `case %d:
+			if err := rset.Scan(P[:%d]...); err != nil {
+				return err
+			}
+			output.%s = append(output.%s, %s)
+		`, i, len(arg.TableOf.RecordOf), name, name, arg.getFromRset("I"))
+	}
+	fmt.Fprintf(w, `}
+						}
+						call.AddRows(n)
+						if n != 0 {
+							return nil
+						}
+						if err := rset.Err(); err != nil {
+							return err
+						}
+					}
+					if idx++; idx < %d && !rset.NextResultSet() {
+						// godror wraps io.EOF after the last result set
+						if err := rset.Err(); err != nil && !errors.Is(err, io.EOF) {
+							return err
+						}
+						break
+					}
+				}
+				return io.EOF
+			},
+		})
+	}
+	`, len(fun.Results))
+}
+
 func (arg Argument) getFromRset(rsetRow string) string {
 	buf := Buffers.Get()
 	defer Buffers.Put(buf)
@@ -1051,21 +1562,14 @@ func (arg Argument) getConvTableRec(
 	paramName string,
 	tableSize uint,
 	parent Argument,
+	isMap bool,
 ) ([]string, []string) {
 	absName := "x__" + name[0] + "__" + name[1]
 	typ, err := arg.goType(true)
 	if err != nil {
 		panic(err)
 	}
-	oraTyp := typ
-	switch oraTyp {
-	case "custom.Date", "custom.DateTime":
-		oraTyp = "time.Time"
-	case "float64":
-		oraTyp = "float64"
-	case "int32":
-		oraTyp = "int32"
-	}
+	oraTyp := oraElemType(typ)
 	if arg.IsInput() {
 		lengthS := "len(input." + name[0] + ")"
 		too, _ := arg.ToOra(absName+"[i]", "v."+name[1], arg.Direction)
@@ -1073,17 +1577,33 @@ func (arg Argument) getConvTableRec(
 		if arg.IsOutput() {
 			setParams = fmt.Sprintf("sql.Out{Dest:&%s,In:true} //gctr1", absName)
 		}
-		convIn = append(convIn, fmt.Sprintf(`
+		if isMap {
+			keys := "x__" + name[0] + "__keys"
+			convIn = append(convIn, fmt.Sprintf(`
+			%s := make([]%s, len(%s), %d)  // gctr1m
+			for i, k := range %s {
+				v := input.%s[k]
+				if v == nil { continue }
+				%s
+			} // gctr1m
+			%s = %s`,
+				absName, oraTyp, keys, tableSize,
+				keys,
+				name[0],
+				too,
+				paramName, setParams))
+		} else {
+			convIn = append(convIn, fmt.Sprintf(`
 			%s := make([]%s, %s, %d)  // gctr1
 			for i,v := range input.%s {
 				%s
 			} // gctr1
 			%s = %s`,
-			absName, oraTyp, lengthS, tableSize,
-			name[0],
-			too,
-			paramName, setParams))
-		_ = too
+				absName, oraTyp, lengthS, tableSize,
+				name[0],
+				too,
+				paramName, setParams))
+		}
 	}
 	if arg.IsOutput() {
 		if !arg.IsInput() {
@@ -1097,6 +1617,30 @@ func (arg Argument) getConvTableRec(
 		if err != nil {
 			panic(err)
 		}
+		if isMap {
+			keys := "x__" + name[0] + "__keys"
+			convert := arg.FromOra(fmt.Sprintf("output.%s[k].%s", name[0], name[1]), "v", "v")
+			if !Gogo && oraTyp == "time.Time" {
+				convert = fmt.Sprintf("output.%s[k].%s = timestamppb.New(v)", name[0], name[1])
+			}
+			convOut = append(convOut,
+				fmt.Sprintf(`for i, v := range %s { // gctr3m
+			if i >= len(%s) { break }
+			k := %s[i]
+			if output.%s[k] == nil {
+				output.%s[k] = new(%s)
+			}
+			%s // gctr3m
+		}`,
+					absName,
+					keys,
+					keys,
+					name[0],
+					name[0], withPb(CamelCase(got[1:])),
+					convert,
+				))
+			return convIn, convOut
+		}
 		convert := arg.FromOra(fmt.Sprintf("output.%s[i].%s", name[0], name[1]), "v", "v")
 		if !Gogo && oraTyp == "time.Time" {
 			convert = fmt.Sprintf("output.%s[i].%s = timestamppb.New(v)", name[0], name[1])
@@ -1125,6 +1669,88 @@ func (arg Argument) getConvTableRec(
 	return convIn, convOut
 }
 
+// getConvMapKeys binds the keys of the map (INDEX BY VARCHAR2) name as a parallel array,
+// and resets the output map.
+func (arg Argument) getConvMapKeys(
+	convIn, convOut []string,
+	name, paramName string,
+	tableSize int,
+) ([]string, []string) {
+	keys := "x__" + name + "__keys"
+	convIn = append(convIn, fmt.Sprintf("%s := make([]string, 0, %d)  // gcmk1", keys, tableSize))
+	if arg.IsInput() {
+		convIn = append(convIn, fmt.Sprintf(`for k := range input.%s { %s = append(%s, k) }  // gcmk1`,
+			name, keys, keys))
+	}
+	if !arg.IsOutput() {
+		convIn = append(convIn, fmt.Sprintf("%s = %s  // gcmk1", paramName, keys))
+		return convIn, convOut
+	}
+	convIn = append(convIn, fmt.Sprintf("%s = sql.Out{Dest:&%s, In:%t}  // gcmk1", paramName, keys, arg.IsInput()))
+	convOut = append(convOut, fmt.Sprintf("custom.ResetMap(&output.%s, len(%s))  // gcmk2", name, keys))
+	return convIn, convOut
+}
+
+// getConvMap binds the map of simple values (INDEX BY VARCHAR2) name as two parallel arrays: keys and values.
+func (arg Argument) getConvMap(
+	convIn, convOut []string,
+	name, keysParam, paramName string,
+	tableSize int,
+) ([]string, []string) {
+	convIn, convOut = arg.getConvMapKeys(convIn, convOut, name, keysParam, tableSize)
+	elem := *arg.TableOf
+	elem.Direction = arg.Direction
+	typ, err := elem.goType(true)
+	if err != nil {
+		panic(err)
+	}
+	oraTyp := oraElemType(typ)
+	keys, vals := "x__"+name+"__keys", "x__"+name+"__vals"
+	if arg.IsInput() {
+		too, _ := elem.ToOra(vals+"[i]", "v", elem.Direction)
+		convIn = append(convIn, fmt.Sprintf(`
+			%s := make([]%s, len(%s), %d)  // gcm1
+			for i, k := range %s {
+				v := input.%s[k]
+				%s
+			} // gcm1`,
+			vals, oraTyp, keys, tableSize,
+			keys,
+			name,
+			too))
+	} else {
+		convIn = append(convIn, fmt.Sprintf("%s := make([]%s, 0, %d)  // gcm1", vals, oraTyp, tableSize))
+	}
+	if !arg.IsOutput() {
+		convIn = append(convIn, fmt.Sprintf("%s = %s  // gcm1", paramName, vals))
+		return convIn, convOut
+	}
+	convIn = append(convIn, fmt.Sprintf("%s = sql.Out{Dest:&%s, In:%t}  // gcm1", paramName, vals, arg.IsInput()))
+	convert := elem.FromOra(fmt.Sprintf("output.%s[k]", name), "v", "v")
+	if !Gogo && oraTyp == "time.Time" {
+		convert = fmt.Sprintf("output.%s[k] = timestamppb.New(v)", name)
+	}
+	convOut = append(convOut, fmt.Sprintf(`for i, v := range %s { // gcm2
+			if i >= len(%s) { break }
+			k := %s[i]
+			%s
+		} // gcm2`,
+		vals,
+		keys,
+		keys,
+		convert))
+	return convIn, convOut
+}
+
+// oraElemType returns the type of the bound array's elements, for the Go type.
+func oraElemType(typ string) string {
+	switch typ {
+	case "custom.Date", "custom.DateTime":
+		return "time.Time"
+	}
+	return typ
+}
+
 var varNames = make(map[string]map[string]string, 4)
 
 func getVarName(funName, varName, prefix string) string {
@@ -1188,4 +1814,20 @@ func FromContext(ctx context.Context) *slog.Logger {
 	return logger
 }
 
+type ctxLobReaders struct{}
+
+// WithLobReaders returns a context carrying the readers of the uploaded LOB inputs, by argument name.
+func WithLobReaders(ctx context.Context, readers map[string]io.Reader) context.Context {
+	return context.WithValue(ctx, ctxLobReaders{}, readers)
+}
+
+// LobReader returns the reader of the uploaded LOB input, or dflt if it has not been uploaded.
+func LobReader(ctx context.Context, name string, dflt io.Reader) io.Reader {
+	readers, _ := ctx.Value(ctxLobReaders{}).(map[string]io.Reader)
+	if r := readers[name]; r != nil {
+		return r
+	}
+	return dflt
+}
+
 // vim: se noet fileencoding=utf-8:
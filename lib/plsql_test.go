@@ -5,6 +5,8 @@
 package oracall
 
 import (
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/kylelemons/godebug/diff"
@@ -22,3 +24,41 @@ func TestOne(t *testing.T) {
 		}
 	}
 }
+
+func TestOmitCallArgs(t *testing.T) {
+	const plsql = "BEGIN\n  Pkg.f(p_a=>NVL(:1, 'a,b)'),\n\t\tp_b=>v001,\n\t\tp_c=>:2);\nEND;\n"
+	args := []CallArg{{Name: "p_a", Value: "NVL(:1, 'a,b)')"}, {Name: "p_b", Value: "v001"}, {Name: "p_c", Value: ":2"}}
+	for _, tc := range []struct {
+		omit []string
+		want string
+	}{
+		{omit: []string{"p_b"}, want: "Pkg.f(p_a=>NVL(:1, 'a,b)'),\n\t\tp_c=>:2);"},
+		{omit: []string{"p_a", "p_c"}, want: "Pkg.f(p_b=>v001);"},
+		{omit: []string{"p_a", "p_b", "p_c"}, want: "Pkg.f;"},
+	} {
+		if got := OmitCallArgs(plsql, "Pkg.f", args, tc.omit...); !strings.Contains(got, tc.want) {
+			t.Errorf("%q: got %s", tc.omit, got)
+		}
+	}
+
+	id := UserArgument{PackageName: "PKG", ObjectName: "DEFS", ObjectID: 1, SubprogramID: 15,
+		ArgumentName: "P_ID", InOut: "IN", DataType: "NUMBER", PlsType: "NUMBER"}
+	name := id
+	name.ArgumentName, name.Position, name.DataType, name.PlsType, name.CharLength, name.Defaulted = "P_NAME", 2, "VARCHAR2", "VARCHAR2", 30, true
+	functions := ParseArgumentsIter(slices.Values([][]UserArgument{{id, name}}), nil)
+	qry, _, callArgs, err := functions[0].PlsqlCall()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(callArgs) != 2 || !strings.Contains(qry, callText("Pkg.defs", callArgs)) {
+		t.Fatalf("%+v not in %s", callArgs, qry)
+	}
+	if got := OmitCallArgs(qry, "Pkg.defs", callArgs, "p_name"); strings.Contains(got, "p_name=>") ||
+		!strings.Contains(got, "Pkg.defs(p_id=>"+callArgs[0].Value+");") {
+		t.Errorf("got %s", got)
+	}
+	if _, callFun := functions[0].PlsqlBlock(""); !strings.Contains(callFun,
+		`oracall.OmitCallArgs(qry, "Pkg.defs", []oracall.CallArg{{Name: "p_id", Value: "`+callArgs[0].Value+`"}, {Name: "p_name", Value: "`+callArgs[1].Value+`"}}, omit...)`) {
+		t.Errorf("got %s", callFun)
+	}
+}
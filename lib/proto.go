@@ -11,6 +11,8 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -23,6 +25,9 @@ var SkipMissingTableOf = true
 var Gogo bool
 var NumberAsString bool
 
+// TxService adds the <Pkg>Tx service, beginning the transactions spanning several calls, to the generated .proto.
+var TxService bool
+
 //go:generate sh ./download-protoc.sh
 //go:generate go install github.com/golang/protobuf/protoc-gen-go@latest
 //go:generate go install github.com/planetscale/vtprotobuf/cmd/protoc-gen-go-vtproto@latest
@@ -30,6 +35,12 @@ var NumberAsString bool
 // build: protoc --go_out=. --go-grpc_out=. my.proto
 
 func SaveProtobuf(ctx context.Context, dst io.Writer, functions []Function, pkg, path string) error {
+	return SaveProtobufWithLock(ctx, dst, functions, pkg, path, nil)
+}
+
+// SaveProtobufWithLock is like SaveProtobuf, but numbers the message fields using lock,
+// and records the new numbers in it.
+func SaveProtobufWithLock(ctx context.Context, dst io.Writer, functions []Function, pkg, path string, lock *FieldLock) error {
 	logger := zlog.SFromContext(ctx)
 	var err error
 	w := errWriter{Writer: dst, err: &err}
@@ -41,7 +52,7 @@ func SaveProtobuf(ctx context.Context, dst io.Writer, functions []Function, pkg,
 option go_package = %q;`, pkg, path)
 	}
 	for _, fun := range functions {
-		if len(fun.Tag) != 0 {
+		if len(fun.Tag) != 0 || fun.Timeout != 0 || fun.Tx != "" || len(fun.Errors) != 0 || fun.hasSecret() {
 			io.WriteString(w, `
 import "github.com/tgulacsi/oracall/orasrv/tag.proto";
 `)
@@ -63,37 +74,48 @@ FunLoop:
 	for _, fun := range functions {
 		//b, _ := json.Marshal(struct{Name, Documentation string}{Name:fun.Name(), Documentation:fun.Documentation})
 		//fmt.Println(string(b))
-		fName := fun.name
+		fName := fun.baseName()
 		if fun.alias != "" {
 			fName = fun.alias
 		}
 		fName = strings.ToLower(fName)
-		if err := fun.SaveProtobuf(&buf, seen); err != nil {
+		if err := fun.saveProtobuf(&buf, seen, lock); err != nil {
 			if SkipMissingTableOf && (errors.Is(err, ErrMissingTableOf) ||
 				errors.Is(err, ErrUnknownSimpleType)) {
 				logger.Info("SKIP function, missing TableOf info", "function", fName)
 				continue FunLoop
 			}
-			return fmt.Errorf("%s: %w", fun.name, err)
+			return fmt.Errorf("%s: %w", fun.baseName(), err)
 		}
 		var streamQual string
 		if fun.HasCursorOut() {
 			streamQual = "stream "
 		}
-		name := CamelCase(dot2D.Replace(fName))
+		name := fun.RPCName()
 		var comment string
 		if fun.Documentation != "" {
 			comment = asComment(fun.Documentation, "")
 		} else if logger.Enabled(ctx, slog.LevelDebug) {
-			logger.Warn("missing documentation", "function", fun.name)
+			logger.Warn("missing documentation", "function", fun.baseName())
 		}
-		if len(fun.Tag) == 0 {
-			tags.Reset()
-		} else {
+		tags.Reset()
+		if len(fun.Tag) != 0 || fun.Timeout != 0 || fun.Tx != "" || len(fun.Errors) != 0 || fun.Idempotent {
 			tags.WriteString("\n")
+			if fun.Idempotent {
+				tags.WriteString("\toption idempotency_level = IDEMPOTENT;\n")
+			}
 			for _, t := range fun.Tag {
 				fmt.Fprintf(&tags, "\toption (oracall.orasrv.tag) = %q;\n", t)
 			}
+			if fun.Timeout != 0 {
+				fmt.Fprintf(&tags, "\toption (oracall.orasrv.timeout) = %q;\n", fun.Timeout)
+			}
+			if fun.Tx != "" {
+				fmt.Fprintf(&tags, "\toption (oracall.orasrv.tx) = %q;\n", fun.Tx)
+			}
+			for _, e := range fun.Errors {
+				fmt.Fprintf(&tags, "\toption (oracall.orasrv.error) = %q;\n", e)
+			}
 		}
 		services = append(services,
 			fmt.Sprintf(`%srpc %s (%s) returns (%s%s) {%s}`,
@@ -105,6 +127,16 @@ FunLoop:
 				tags.String(),
 			),
 		)
+		if len(fun.uploads()) != 0 {
+			services = append(services,
+				fmt.Sprintf("// %sUpload is %s, with the LOB inputs received in chunks: the first message carries the other arguments.\n\trpc %sUpload (stream %s) returns (%s) {%s}",
+					name, name, name,
+					CamelCase(fun.getStructName(false, false)),
+					CamelCase(fun.getStructName(true, false)),
+					tags.String(),
+				),
+			)
+		}
 	}
 	{
 		b := buf.Bytes()
@@ -122,21 +154,61 @@ import "google/protobuf/timestamp.proto";
 	}
 	w.Write([]byte("}"))
 
+	if !TxService {
+		return nil
+	}
+	fmt.Fprintf(w, `
+
+// OracallTxBegin begins a transaction spanning several calls.
+message OracallTxBegin {
+	// the transaction is rolled back after being idle for so many seconds (server default if zero).
+	uint32 idle_seconds = 1;
+	bool read_only = 2;
+	bool serializable = 3;
+}
+
+// OracallTransaction is joined by the calls having its id in the %q metadata.
+message OracallTransaction {
+	string id = 1;
+	uint32 idle_seconds = 2;
+}
+
+service %sTx {
+	rpc Begin (OracallTxBegin) returns (OracallTransaction) {}
+	rpc Commit (OracallTransaction) returns (OracallTransaction) {}
+	rpc Rollback (OracallTransaction) returns (OracallTransaction) {}
+}`, TxMetadataKey, CamelCase(pkg))
+
 	return nil
 }
 
+// RPCName returns the name of the rpc of the function in the service.
+func (f Function) RPCName() string {
+	nm := f.baseName()
+	if f.alias != "" {
+		nm = f.alias
+	}
+	return CamelCase(dot2D.Replace(strings.ToLower(nm)))
+}
+
 func (f Function) SaveProtobuf(dst io.Writer, seen map[string]struct{}) error {
+	return f.saveProtobuf(dst, seen, nil)
+}
+func (f Function) saveProtobuf(dst io.Writer, seen map[string]struct{}, lock *FieldLock) error {
+	if err := f.checkResults(); err != nil {
+		return err
+	}
 	var buf bytes.Buffer
-	if err := f.saveProtobufDir(&buf, seen, false); err != nil {
+	if err := f.saveProtobufDir(&buf, seen, lock, false); err != nil {
 		return fmt.Errorf("%s: %w", "input", err)
 	}
-	if err := f.saveProtobufDir(&buf, seen, true); err != nil {
+	if err := f.saveProtobufDir(&buf, seen, lock, true); err != nil {
 		return fmt.Errorf("%s: %w", "output", err)
 	}
 	_, err := dst.Write(buf.Bytes())
 	return err
 }
-func (f Function) saveProtobufDir(dst io.Writer, seen map[string]struct{}, out bool) error {
+func (f Function) saveProtobufDir(dst io.Writer, seen map[string]struct{}, lock *FieldLock, out bool) error {
 	dirmap, dirname := DIR_IN, "input"
 	if out {
 		dirmap, dirname = DIR_OUT, "output"
@@ -151,24 +223,30 @@ func (f Function) saveProtobufDir(dst io.Writer, seen map[string]struct{}, out b
 	if out && f.Returns != nil {
 		args = append(args, *f.Returns)
 	}
+	if out {
+		args = append(args, f.Results...)
+	}
 
-	nm := f.name
+	nm := f.baseName()
 	if f.alias != "" {
 		nm = f.alias
 	}
 	return protoWriteMessageTyp(dst,
 		CamelCase(dot2D.Replace(strings.ToLower(nm))+"__"+dirname),
-		seen, getDirDoc(f.Documentation, dirmap), args...)
+		seen, lock, getDirDoc(f.Documentation, dirmap), args...)
 }
 
 var dot2D = strings.NewReplacer(".", "__")
 
-func protoWriteMessageTyp(dst io.Writer, msgName string, seen map[string]struct{}, D argDocs, args ...Argument) error {
-	for _, arg := range args {
+func protoWriteMessageTyp(dst io.Writer, msgName string, seen map[string]struct{}, lock *FieldLock, D argDocs, args ...Argument) error {
+	names := make([]string, len(args))
+	for i, arg := range args {
 		if arg.Flavor == FLAVOR_TABLE && arg.TableOf == nil {
 			panic(fmt.Errorf("protoWriteMessageTyp: no table of data for %s.%s (%v): %w", msgName, arg, arg, ErrMissingTableOf))
 		}
+		names[i] = replHidden(arg.Name)
 	}
+	numbers, reserved := lock.assign(msgName, names)
 
 	var err error
 	w := &errWriter{Writer: dst, err: &err}
@@ -204,14 +282,28 @@ func protoWriteMessageTyp(dst io.Writer, msgName string, seen map[string]struct{
 			got = mkRecTypName(arg.Name)
 		}
 		typ, pOpts := protoType(got, arg.Name, arg.AbsType)
+		if arg.Secret {
+			if pOpts == nil {
+				pOpts = make(protoOptions, 1)
+			}
+			pOpts[secretOption] = true
+		}
 		var optS string
 		if pOpts != nil {
 			if s := pOpts.String(); s != "" {
 				optS = " " + s
 			}
 		}
+		if arg.IsMap() {
+			rule = "" // map<string, typ>, typ is set after the record's message name
+		}
 		if arg.Flavor == FLAVOR_SIMPLE || arg.Flavor == FLAVOR_TABLE && arg.TableOf.Flavor == FLAVOR_SIMPLE {
-			fmt.Fprintf(w, "%s\t// %s\n\t%s%s %s = %d%s;\n", asComment(D.Map[aName], "\t"), arg.AbsType, rule, typ, aName, i+1, optS)
+			if arg.IsMap() {
+				typ = "map<string, " + typ + ">"
+			} else if rule == "" && arg.IsOptional() {
+				rule = "optional " // unset means the DEFAULT value
+			}
+			fmt.Fprintf(w, "%s\t// %s\n\t%s%s %s = %d%s;\n", asComment(D.Map[aName], "\t"), arg.AbsType, rule, typ, aName, numbers[i], optS)
 			continue
 		}
 		typ = CamelCase(strings.Replace(strings.ToUpper(typ), "%ROWTYPE", "_rt", 1))
@@ -232,19 +324,42 @@ func protoWriteMessageTyp(dst io.Writer, msgName string, seen map[string]struct{
 					}
 				}
 			}
-			if err = protoWriteMessageTyp(buf, typ, seen, argDocs{Pre: D.Map[aName]}, subArgs...); err != nil {
+			if err = protoWriteMessageTyp(buf, typ, seen, lock, argDocs{Pre: D.Map[aName]}, subArgs...); err != nil {
 				// logger.Error("protoWriteMessageTyp", "error", err)
 				return err
 			}
 		}
-		fmt.Fprintf(w, "\t%s%s %s = %d%s;\n", rule, typ, aName, i+1, optS)
+		if arg.IsMap() {
+			typ = "map<string, " + typ + ">"
+		}
+		fmt.Fprintf(w, "\t%s%s %s = %d%s;\n", rule, typ, aName, numbers[i], optS)
 	}
+	writeReserved(w, reserved)
 	io.WriteString(w, "}\n")
 	w.Write(buf.Bytes())
 
 	return err
 }
 
+// writeReserved writes the reserved numbers and names of the removed fields.
+func writeReserved(w io.Writer, reserved []ReservedField) {
+	if len(reserved) == 0 {
+		return
+	}
+	nums := make([]string, 0, len(reserved))
+	names := make([]string, 0, len(reserved))
+	for _, r := range reserved {
+		nums = append(nums, strconv.Itoa(r.Number))
+		if r.Name != "" {
+			names = append(names, strconv.Quote(r.Name))
+		}
+	}
+	fmt.Fprintf(w, "\treserved %s;\n", strings.Join(nums, ", "))
+	if len(names) != 0 {
+		fmt.Fprintf(w, "\treserved %s;\n", strings.Join(names, ", "))
+	}
+}
+
 func protoType(got, aName, absType string) (string, protoOptions) {
 	switch trimmed := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(got, "[]"), "*")); trimmed {
 	case "bool", "string":
@@ -341,7 +456,8 @@ func (opts protoOptions) String() string {
 	}
 	var buf bytes.Buffer
 	buf.WriteByte('[')
-	for k, v := range opts {
+	for _, k := range slices.Sorted(maps.Keys(opts)) {
+		v := opts[k]
 		if buf.Len() != 1 {
 			buf.WriteString(", ")
 		}
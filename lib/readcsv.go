@@ -11,12 +11,16 @@ import (
 	"fmt"
 	"io"
 	"iter"
+	"maps"
 	"os"
+	"path"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/UNO-SOFT/zlog/v2/slog"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -24,10 +28,13 @@ import (
 type UserArgument struct {
 	PackageName string `sql:"PACKAGE_NAME"`
 	ObjectName  string `sql:"OBJECT_NAME"`
+	Overload    string `sql:"OVERLOAD" json:",omitzero"`
 	LastDDL     time.Time
 
 	ArgumentName string `sql:"ARGUMENT_NAME"`
 	InOut        string `sql:"IN_OUT"`
+	// Defaulted is true for parameters with a DEFAULT clause.
+	Defaulted bool `sql:"DEFAULTED" json:",omitzero"`
 
 	DataType string `sql:"DATA_TYPE"`
 
@@ -49,6 +56,18 @@ type UserArgument struct {
 	DataPrecision uint8 `sql:"DATA_PRECISION"`
 	DataScale     uint8 `sql:"DATA_SCALE"`
 	DataLevel     uint8 `sql:"DATA_LEVEL"`
+
+	// Implicit is true for the implicit result sets (DBMS_SQL.RETURN_RESULT), described by annotations.
+	Implicit bool `json:",omitzero"`
+}
+
+// FunctionKey returns the key of the function of the argument in PackageCache.Functions:
+// the object name, suffixed with "_" and the overload number for overloaded functions.
+func (ua UserArgument) FunctionKey() string {
+	if ua.Overload == "" {
+		return ua.ObjectName
+	}
+	return ua.ObjectName + "_" + ua.Overload
 }
 
 // ParseCsv reads the given csv file as user_arguments
@@ -98,15 +117,15 @@ func filterArgs(userArgs iter.Seq[UserArgument], filter func(string) bool) iter.
 func groupArgs(userArgs iter.Seq[UserArgument]) iter.Seq[[]UserArgument] {
 	return func(yield func([]UserArgument) bool) {
 		type program struct {
-			PackageName, ObjectName string
-			ObjectID, SubprogramID  uint
+			PackageName, ObjectName, Overload string
+			ObjectID, SubprogramID            uint
 		}
 		var lastProg, zeroProg program
 		args := make([]UserArgument, 0, 4)
 		for ua := range userArgs {
 			actProg := program{
 				ObjectID: ua.ObjectID, SubprogramID: ua.SubprogramID,
-				PackageName: ua.PackageName, ObjectName: ua.ObjectName}
+				PackageName: ua.PackageName, ObjectName: ua.ObjectName, Overload: ua.Overload}
 			if lastProg != zeroProg && lastProg != actProg {
 				if len(args) != 0 {
 					if !yield(args) {
@@ -184,6 +203,13 @@ func NewUACsvWriter(w io.Writer) (*UACsvWriter, error) {
 		switch ft.Type.Kind() {
 		case reflect.Uint, reflect.Uint8:
 			converters[i] = func(v reflect.Value) string { return strconv.FormatUint(v.Uint(), 10) }
+		case reflect.Bool:
+			converters[i] = func(v reflect.Value) string {
+				if v.Bool() {
+					return "Y"
+				}
+				return "N"
+			}
 		default:
 			converters[i] = func(v reflect.Value) string { return v.String() }
 		}
@@ -257,6 +283,10 @@ func NewUACsvReader(r io.Reader) iter.Seq2[UserArgument, error] {
 			conv = func(st reflect.Value, s string) {
 				st.Field(i).SetUint(uint64(mustBeUint(s)))
 			}
+		case reflect.Bool:
+			conv = func(st reflect.Value, s string) {
+				st.Field(i).SetBool(s == "Y")
+			}
 		}
 		csvFields[name] = csvStructIdx{Csv: -1, Struct: i, Conv: conv}
 	}
@@ -327,17 +357,24 @@ func ParseArgumentsIter(userArgs iter.Seq[[]UserArgument], filter func(string) b
 		}
 
 		var fun Function
+		implicit := make(map[*Argument]bool)
 		lastArgs := make(map[int8]*Argument, 8)
 		lastArgs[-1] = &Argument{Flavor: FLAVOR_RECORD}
 		var level int8
 		for i, ua := range uas {
 			row++
 			if i == 0 {
-				fun = Function{Package: ua.PackageName, name: ua.ObjectName, LastDDL: ua.LastDDL}
+				fun = Function{Package: ua.PackageName, name: ua.ObjectName, overload: ua.Overload, LastDDL: ua.LastDDL}
 			}
 
-			level = int8(ua.DataLevel)
+			if level = int8(ua.DataLevel); level == 0 && ua.DataType == "" && ua.ArgumentName == "" {
+				// the only row of a procedure without parameters
+				continue
+			}
 			typeName := ua.TypeOwner + "." + ua.TypeName + "." + ua.TypeSubname + "@" + ua.TypeLink
+			if ua.TypeOwner == "" && ua.TypeName != "" { // not schema-qualified (parsed from source)
+				typeName = typeName[1:]
+			}
 			if ua.TypeSubname == "" && ua.PlsType+"@" == typeName {
 				typeName = ua.TypeOwner + "." + ua.TypeName + "%ROWTYPE"
 			}
@@ -353,6 +390,10 @@ func ParseArgumentsIter(userArgs iter.Seq[[]UserArgument], filter func(string) b
 				ua.DataScale,
 				ua.CharLength,
 			)
+			arg.Defaulted = level == 0 && ua.Defaulted && arg.Direction == DIR_IN
+			if level == 0 && ua.Implicit {
+				implicit[&arg] = true
+			}
 			// logger.Debug("ParseArgument", "level", level, "fun", fun.name, "arg", arg.Name, "type", ua.DataType, "last", lastArgs, "flavor", arg.Flavor, "typeName", typeName, "ua", ua, "arg", arg, "typeSub", ua.TypeSubname, "pls", ua.PlsType)
 			// Possibilities:
 			// 1. SIMPLE
@@ -372,14 +413,21 @@ func ParseArgumentsIter(userArgs iter.Seq[[]UserArgument], filter func(string) b
 				panic(fmt.Sprintf("parent is nil, at level=%d, lastArgs=%v, fun=%v", level, lastArgs, fun))
 			}
 			if parent.Flavor == FLAVOR_TABLE {
+				if parent.IndexBy == "" { // the DB has the index type in the element's row
+					parent.IndexBy = arg.IndexBy
+				}
 				parent.TableOf = &arg
 			} else {
 				parent.RecordOf = append(parent.RecordOf, NamedArgument{Name: arg.Name, Argument: &arg})
 			}
 		}
-		fun.Args = make([]Argument, len(lastArgs[-1].RecordOf))
-		for i, na := range lastArgs[-1].RecordOf {
-			fun.Args[i] = *na.Argument
+		fun.Args = make([]Argument, 0, len(lastArgs[-1].RecordOf))
+		for _, na := range lastArgs[-1].RecordOf {
+			if implicit[na.Argument] {
+				fun.Results = append(fun.Results, *na.Argument)
+			} else {
+				fun.Args = append(fun.Args, *na.Argument)
+			}
 		}
 		functions = append(functions, fun)
 		names = append(names, fun.Name())
@@ -447,6 +495,16 @@ func (a Annotation) String() string {
 		return a.Type + " " + a.FullName()
 	case "max-table-size":
 		return fmt.Sprintf("%s.MaxTableSize=%d", a.FullName(), a.Size)
+	case "stream":
+		return fmt.Sprintf("stream %s=%d", a.FullName(), a.Size)
+	case "upload", "idempotent":
+		return a.Type + " " + a.FullName()
+	case "retry":
+		return fmt.Sprintf("%s.MaxAttempts=%d", a.FullName(), a.Size)
+	case "timeout":
+		return fmt.Sprintf("%s.Timeout=%s", a.FullName(), a.Other)
+	case "tx":
+		return fmt.Sprintf("tx %s=%s", a.FullName(), a.Other)
 	}
 	return a.Type + " " + a.FullName() + "=>" + a.FullOther()
 }
@@ -457,25 +515,41 @@ func ApplyAnnotations(functions []Function, annotations []Annotation) []Function
 	}
 	L := strings.ToLower
 	funcs := make(map[string]*Function, len(functions))
+	// overloads holds the overloads by their real name
+	overloads := make(map[string][]*Function)
 	for i := range functions {
 		f := functions[i]
-		funcs[L(f.RealName())] = &f
+		// the overloads share the real name
+		if f.overload == "" {
+			funcs[L(f.RealName())] = &f
+		} else {
+			funcs[L(f.Name())] = &f
+			overloads[L(f.RealName())] = append(overloads[L(f.RealName())], &f)
+		}
+	}
+	// find returns the function of the name: the overload with its suffixed name (pkg.fn_2),
+	// or all the overloads with their real name (pkg.fn).
+	find := func(nm string) []*Function {
+		if f := funcs[nm]; f != nil {
+			return []*Function{f}
+		}
+		return overloads[nm]
 	}
 	for _, a := range annotations {
 		if a.Name == "" || a.Type == "" {
 			continue
 		}
-		if a.Other == "" && !(a.Type == "private" || a.Type == "handle" || a.Type == "max-table-size") {
+		if a.Other == "" && !(a.Type == "private" || a.Type == "handle" || a.Type == "max-table-size" || a.Type == "stream" || a.Type == "upload" || a.Type == "idempotent" || a.Type == "retry" || a.Type == "secret") {
 			continue
 		}
-		if a.Size <= 0 && a.Type == "max-table-size" {
+		if a.Size <= 0 && (a.Type == "max-table-size" || a.Type == "retry") {
 			continue
 		}
 		switch a.Type {
 		case "private":
-			nm := L(a.FullName())
-			// logger.Info("directive", "private", nm)
-			delete(funcs, nm)
+			fs := find(L(a.FullName()))
+			// logger.Info("directive", "private", fs)
+			maps.DeleteFunc(funcs, func(_ string, f *Function) bool { return slices.Contains(fs, f) })
 		case "rename":
 			nm := L(a.FullName())
 			if f := funcs[nm]; f != nil {
@@ -505,19 +579,120 @@ func ApplyAnnotations(functions []Function, annotations []Annotation) []Function
 			}
 
 		case "max-table-size":
-			nm := L(a.FullName())
-			// logger.Info("directive", "max-table-size", nm, "size", a.Size)
-			if f := funcs[nm]; f != nil && a.Size >= f.maxTableSize {
-				f.maxTableSize = a.Size
+			// logger.Info("directive", "max-table-size", a.FullName(), "size", a.Size)
+			for _, f := range find(L(a.FullName())) {
+				if a.Size >= f.maxTableSize {
+					f.maxTableSize = a.Size
+				}
+			}
+
+		// stream the LOB output (fn.arg) in chunks
+		case "stream":
+			nm, argName, _ := strings.Cut(L(a.Name), ".")
+			size := a.Size
+			if size <= 0 {
+				size = LobChunkSize
+			}
+			for _, f := range find(L(Annotation{Package: a.Package, Name: nm}.FullName())) {
+				if f.Returns != nil && argName == "ret" {
+					ret := *f.Returns
+					ret.ChunkSize = size
+					f.Returns = &ret
+				}
+				f.Args = slices.Clone(f.Args)
+				for i, arg := range f.Args {
+					if L(arg.Name) == argName {
+						f.Args[i].ChunkSize = size
+					}
+				}
+			}
+
+		// accept the LOB input (fn.arg) in chunks, in a client-streaming variant
+		case "upload":
+			nm, argName, _ := strings.Cut(L(a.Name), ".")
+			for _, f := range find(L(Annotation{Package: a.Package, Name: nm}.FullName())) {
+				f.Args = slices.Clone(f.Args)
+				for i, arg := range f.Args {
+					if L(arg.Name) == argName && arg.Direction == DIR_IN && (arg.Type == "CLOB" || arg.Type == "BLOB") {
+						f.Args[i].Upload = true
+					}
+				}
+			}
+
+		// map ORA error codes (or a range, or a predefined exception) to a gRPC code, in ALL functions of the package
+		case "error":
+			codes := a.Name
+			if code, ok := ExceptionCodes[strings.ToUpper(codes)]; ok {
+				codes = strconv.Itoa(code)
+			}
+			for _, f := range funcs {
+				if strings.EqualFold(f.Package, a.Package) {
+					f.Errors = append(f.Errors, codes+"="+strings.ToUpper(a.Other))
+				}
+			}
+
+		case "idempotent":
+			for _, f := range find(L(a.FullName())) {
+				f.Idempotent = true
+			}
+		// override the attempts of the server's RetryPolicy
+		case "retry":
+			for _, f := range find(L(a.FullName())) {
+				f.MaxAttempts = a.Size
+			}
+
+		// redact the argument (fn.arg, or a pattern with %) in the logs, and the fields of its records with such name
+		case "secret":
+			fnPattern, argPattern, ok := strings.Cut(L(a.Name), ".")
+			if !ok {
+				continue
+			}
+			fnPattern = strings.ReplaceAll(fnPattern, "%", "*")
+			for _, f := range funcs {
+				// the suffixed name matches one overload, the real name all of them
+				ok, _ := path.Match(fnPattern, L(f.baseName()))
+				if !ok {
+					ok, _ = path.Match(fnPattern, L(f.name))
+				}
+				if !ok || !strings.EqualFold(f.Package, a.Package) {
+					continue
+				}
+				f.Args = slices.Clone(f.Args)
+				for i := range f.Args {
+					f.Args[i].markSecret(argPattern)
+				}
+				if f.Returns != nil {
+					ret := *f.Returns
+					ret.markSecret(argPattern)
+					f.Returns = &ret
+				}
+			}
+
+		case "timeout":
+			timeout, err := time.ParseDuration(a.Other)
+			if err != nil {
+				slog.Warn("bad timeout annotation", "function", a.FullName(), "timeout", a.Other, "error", err)
+				continue
+			}
+			for _, f := range find(L(a.FullName())) {
+				f.Timeout = timeout
+			}
+
+		// readonly, serializable or nocommit transaction
+		case "tx":
+			mode, err := ParseTxMode(a.Other)
+			if err != nil {
+				slog.Warn("bad tx annotation", "function", a.FullName(), "tx", a.Other, "error", err)
+				continue
+			}
+			for _, f := range find(L(a.FullName())) {
+				f.Tx = mode.String()
 			}
 
 		case "tag":
-			nm := L(a.FullName())
-			if f := funcs[nm]; f != nil {
+			for _, f := range find(L(a.FullName())) {
 				f.Tag = append(f.Tag, a.Other)
-				// logger.Info("directive", "f", nm, "tag", f.Tag)
-			} else {
-				// logger.Warn("no function for tag", "name", nm, "tag", a.Other, "have", funcs)
+				// logger.Info("directive", "f", f.Name(), "tag", f.Tag)
 			}
 		}
 	}
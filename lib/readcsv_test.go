@@ -5,8 +5,12 @@
 package oracall
 
 import (
+	"database/sql"
+	"maps"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 //var flagConnect = flag.String("connect", "", "database DSN to connect to")
@@ -23,3 +27,277 @@ func TestParseCsv(t *testing.T) {
 		}
 	}
 }
+
+func TestStreamAnnotation(t *testing.T) {
+	id := UserArgument{PackageName: "PKG", ObjectName: "DOC", ObjectID: 1, SubprogramID: 3,
+		ArgumentName: "P_ID", InOut: "IN", DataType: "NUMBER", PlsType: "NUMBER"}
+	doc := id
+	doc.ArgumentName, doc.InOut, doc.DataType, doc.PlsType, doc.Position = "P_DOC", "OUT", "BLOB", "BLOB", 1
+	functions := ParseArgumentsIter(slices.Values([][]UserArgument{{id, doc}}), nil)
+	functions = ApplyAnnotations(functions, []Annotation{{Package: "PKG", Type: "stream", Name: "doc.p_doc", Size: 1024}})
+	if len(functions) != 1 {
+		t.Fatalf("got %d functions", len(functions))
+	}
+	f := functions[0]
+	if !f.Args[1].IsStreamed() || f.Args[1].ChunkSize != 1024 || !f.HasCursorOut() {
+		t.Fatalf("not streamed: %+v", f.Args[1])
+	}
+	if _, callFun := f.PlsqlBlock(""); !strings.Contains(callFun, "custom.NewChunks(") || !strings.Contains(callFun, "output.Reset()") {
+		t.Errorf("got %s", callFun)
+	}
+}
+
+func TestUploadAnnotation(t *testing.T) {
+	id := UserArgument{PackageName: "PKG", ObjectName: "PUT_DOC", ObjectID: 1, SubprogramID: 4,
+		ArgumentName: "P_ID", InOut: "IN", DataType: "NUMBER", PlsType: "NUMBER"}
+	doc := id
+	doc.ArgumentName, doc.DataType, doc.PlsType, doc.Position = "P_DOC", "CLOB", "CLOB", 1
+	functions := ParseArgumentsIter(slices.Values([][]UserArgument{{id, doc}}), nil)
+	functions = ApplyAnnotations(functions, []Annotation{{Package: "PKG", Type: "upload", Name: "put_doc.p_doc"}})
+	f := functions[0]
+	if uploads := f.uploads(); len(uploads) != 1 || uploads[0].Name != "p_doc" {
+		t.Fatalf("got %+v", uploads)
+	}
+	if _, callFun := f.PlsqlBlock(""); !strings.Contains(callFun, "PutDocUpload(stream pb.Pkg_PutDocUploadServer)") ||
+		!strings.Contains(callFun, `oracall.LobReader(ctx, "p_doc", `) {
+		t.Errorf("got %s", callFun)
+	}
+	var buf strings.Builder
+	if err := SaveProtobuf(t.Context(), &buf, functions, "pkg", ""); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, "rpc PutDocUpload (stream PutDoc_Input) returns (PutDoc_Output)") {
+		t.Errorf("got %s", s)
+	}
+}
+
+func TestReplaceAnnotation(t *testing.T) {
+	calc := UserArgument{PackageName: "PKG", ObjectName: "CALC", ObjectID: 1, SubprogramID: 11,
+		ArgumentName: "P_ID", InOut: "IN", DataType: "NUMBER", PlsType: "NUMBER"}
+	calcXML := UserArgument{PackageName: "PKG", ObjectName: "CALC_XML", ObjectID: 1, SubprogramID: 12,
+		ArgumentName: "P_XML", InOut: "IN/OUT", DataType: "CLOB", PlsType: "CLOB"}
+	get1 := UserArgument{PackageName: "PKG", ObjectName: "GET", Overload: "1", ObjectID: 1, SubprogramID: 13,
+		ArgumentName: "P_ID", InOut: "IN", DataType: "NUMBER", PlsType: "NUMBER"}
+	get2 := get1
+	get2.Overload, get2.SubprogramID, get2.ArgumentName = "2", 14, "P_NAME"
+	functions := ParseArgumentsIter(slices.Values([][]UserArgument{{calc}, {calcXML}, {get1}, {get2}}), nil)
+	functions = ApplyAnnotations(functions, []Annotation{{Package: "PKG", Type: "replace", Name: "calc", Other: "calc_xml"}})
+	// the replaced function is found by its real name in the next pass
+	functions = ApplyAnnotations(functions, []Annotation{
+		{Package: "PKG", Type: "max-table-size", Name: "calc_xml", Size: 100},
+		{Package: "PKG", Type: "max-table-size", Name: "get_2", Size: 200},
+	})
+	if len(functions) != 3 {
+		t.Fatalf("got %d functions", len(functions))
+	}
+	got := make(map[string]int, len(functions))
+	for _, f := range functions {
+		got[f.Name()] = f.maxTableSize
+	}
+	if want := map[string]int{"Pkg.calc": 100, "Pkg.get_1": 0, "Pkg.get_2": 200}; !maps.Equal(got, want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+}
+
+func TestTimeoutAnnotation(t *testing.T) {
+	id := UserArgument{PackageName: "PKG", ObjectName: "SLOW", ObjectID: 1, SubprogramID: 5,
+		ArgumentName: "P_ID", InOut: "IN", DataType: "NUMBER", PlsType: "NUMBER"}
+	functions := ParseArgumentsIter(slices.Values([][]UserArgument{{id}}), nil)
+	functions = ApplyAnnotations(functions, []Annotation{
+		{Package: "PKG", Type: "timeout", Name: "slow", Other: "30s"},
+		{Package: "PKG", Type: "timeout", Name: "slow", Other: "soon"}, // ignored with a warning
+	})
+	f := functions[0]
+	if f.Timeout != 30*time.Second {
+		t.Fatalf("got %v", f.Timeout)
+	}
+	if _, callFun := f.PlsqlBlock(""); !strings.Contains(callFun, "context.WithTimeout(ctx, 30000000000)") {
+		t.Errorf("got %s", callFun)
+	}
+	var buf strings.Builder
+	if err := SaveProtobuf(t.Context(), &buf, functions, "pkg", ""); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, `option (oracall.orasrv.timeout) = "30s";`) {
+		t.Errorf("got %s", s)
+	}
+}
+
+func TestOverloadAnnotation(t *testing.T) {
+	byID := UserArgument{PackageName: "PKG", ObjectName: "GET_DATA", Overload: "1", ObjectID: 1, SubprogramID: 8,
+		ArgumentName: "P_ID", InOut: "IN", DataType: "NUMBER", PlsType: "NUMBER"}
+	byName := byID
+	byName.Overload, byName.SubprogramID = "2", 9
+	byName.ArgumentName, byName.DataType, byName.PlsType = "P_PASSWORD", "VARCHAR2", "VARCHAR2"
+	functions := ParseArgumentsIter(slices.Values([][]UserArgument{{byID}, {byName}}), nil)
+	functions = ApplyAnnotations(functions, []Annotation{
+		{Package: "PKG", Type: "idempotent", Name: "get_data"},        // both
+		{Package: "PKG", Type: "tag", Name: "get_data_2", Other: "x"}, // only the second
+		{Package: "PKG", Type: "secret", Name: "get_data.p_password"},
+		{Package: "PKG", Type: "secret", Name: "get_data_1.p_id"},
+	})
+	if len(functions) != 2 {
+		t.Fatalf("got %d functions", len(functions))
+	}
+	slices.SortFunc(functions, func(a, b Function) int { return strings.Compare(a.Name(), b.Name()) })
+	first, second := functions[0], functions[1]
+	if !first.Idempotent || !second.Idempotent {
+		t.Errorf("idempotent: got %t, %t", first.Idempotent, second.Idempotent)
+	}
+	if len(first.Tag) != 0 || !slices.Equal(second.Tag, []string{"x"}) {
+		t.Errorf("tag: got %q, %q", first.Tag, second.Tag)
+	}
+	if !first.Args[0].Secret || !second.Args[0].Secret {
+		t.Errorf("secret: got %t, %t", first.Args[0].Secret, second.Args[0].Secret)
+	}
+
+	functions = ApplyAnnotations(ParseArgumentsIter(slices.Values([][]UserArgument{{byID}, {byName}}), nil),
+		[]Annotation{{Package: "PKG", Type: "private", Name: "get_data"}})
+	if len(functions) != 0 {
+		t.Errorf("private: got %d functions", len(functions))
+	}
+}
+
+func TestErrorAnnotation(t *testing.T) {
+	id := UserArgument{PackageName: "PKG", ObjectName: "GET", ObjectID: 1, SubprogramID: 6,
+		ArgumentName: "P_ID", InOut: "IN", DataType: "NUMBER", PlsType: "NUMBER"}
+	functions := ParseArgumentsIter(slices.Values([][]UserArgument{{id}}), nil)
+	functions = ApplyAnnotations(functions, []Annotation{
+		{Package: "PKG", Type: "error", Name: "20001..20099", Other: "invalid_argument"},
+		{Package: "PKG", Type: "error", Name: "TOO_MANY_ROWS", Other: "ABORTED"},
+	})
+	if got, want := functions[0].Errors, []string{"20001..20099=INVALID_ARGUMENT", "1422=ABORTED"}; !slices.Equal(got, want) {
+		t.Fatalf("got %q, wanted %q", got, want)
+	}
+	var buf strings.Builder
+	if err := SaveProtobuf(t.Context(), &buf, functions, "pkg", ""); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, `import "github.com/tgulacsi/oracall/orasrv/tag.proto";`) ||
+		!strings.Contains(s, `option (oracall.orasrv.error) = "1422=ABORTED";`) {
+		t.Errorf("got %s", s)
+	}
+}
+
+func TestIdempotentAnnotation(t *testing.T) {
+	id := UserArgument{PackageName: "PKG", ObjectName: "LOOKUP", ObjectID: 1, SubprogramID: 7,
+		ArgumentName: "P_ID", InOut: "IN", DataType: "NUMBER", PlsType: "NUMBER"}
+	functions := ParseArgumentsIter(slices.Values([][]UserArgument{{id}}), nil)
+	functions = ApplyAnnotations(functions, []Annotation{
+		{Package: "PKG", Type: "idempotent", Name: "lookup"},
+		{Package: "PKG", Type: "retry", Name: "lookup", Size: 5},
+	})
+	f := functions[0]
+	if !f.Retryable() || f.MaxAttempts != 5 {
+		t.Fatalf("got %+v", f)
+	}
+	if _, callFun := f.PlsqlBlock(""); !strings.Contains(callFun, "s.RetryPolicy.Do(ctx, 5,") ||
+		!strings.Contains(callFun, "if oracall.JoinsTx(ctx) {\n\t\treturn s.callLookup(ctx, input)") ||
+		!strings.Contains(callFun, "func (s *oracallServer) callLookup(ctx context.Context") {
+		t.Errorf("got %s", callFun)
+	}
+	var buf strings.Builder
+	if err := SaveProtobuf(t.Context(), &buf, functions, "pkg", ""); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, "option idempotency_level = IDEMPOTENT;") {
+		t.Errorf("got %s", s)
+	}
+}
+
+func TestSecretAnnotation(t *testing.T) {
+	login := UserArgument{PackageName: "PKG", ObjectName: "LOGIN", ObjectID: 1, SubprogramID: 8,
+		ArgumentName: "P_LOGIN_NEV", InOut: "IN", DataType: "VARCHAR2", PlsType: "VARCHAR2", CharLength: 30}
+	passw := login
+	passw.ArgumentName, passw.Position = "P_JELSZO", 2
+	functions := ParseArgumentsIter(slices.Values([][]UserArgument{{login, passw}}), nil)
+	// without annotations, the input of a function having a password is not logged
+	if _, callFun := functions[0].PlsqlBlock(""); strings.Contains(callFun, `"input", input`) ||
+		strings.Contains(callFun, `fmt.Errorf("%v: %w", params, err)`) || strings.Contains(callFun, "`result params`, params") {
+		t.Errorf("got %s", callFun)
+	}
+	functions = ApplyAnnotations(functions, []Annotation{{Package: "PKG", Type: "secret", Name: "%.%jelszo"}})
+	f := functions[0]
+	if f.Args[0].Secret || !f.Args[1].Secret {
+		t.Fatalf("got %+v", f.Args)
+	}
+	if _, callFun := f.PlsqlBlock(""); !strings.Contains(callFun, `"input", oracall.Redact(input)`) ||
+		!strings.Contains(callFun, `s.DBLog(ctx, tx, funName, oracall.Redact(input))`) ||
+		!strings.Contains(callFun, "`output`, oracall.Redact(output)") ||
+		strings.Contains(callFun, "`result params`, params") ||
+		strings.Contains(callFun, `fmt.Errorf("%v: %w", params, err)`) {
+		t.Errorf("got %s", callFun)
+	}
+	var buf strings.Builder
+	if err := SaveProtobuf(t.Context(), &buf, functions, "pkg", ""); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, `import "github.com/tgulacsi/oracall/orasrv/tag.proto";`) ||
+		!strings.Contains(s, `p_jelszo = 2 [(oracall.orasrv.secret)=true];`) {
+		t.Errorf("got %s", s)
+	}
+
+	// the nested arguments may be shared by the functions
+	shared := &Argument{Name: "jelszo"}
+	rec := Argument{Name: "p_rec", Flavor: FLAVOR_RECORD, RecordOf: []NamedArgument{{Name: "jelszo", Argument: shared}}}
+	tab := Argument{Name: "p_tab", Flavor: FLAVOR_TABLE, TableOf: &rec}
+	functions = ApplyAnnotations([]Function{
+		{Package: "PKG", name: "A", Args: []Argument{tab}},
+		{Package: "PKG", name: "B", Args: []Argument{tab}},
+	}, []Annotation{{Package: "PKG", Type: "secret", Name: "a.jelszo"}})
+	for _, f := range functions {
+		if got, want := f.Args[0].TableOf.RecordOf[0].Secret, f.name == "A"; got != want {
+			t.Errorf("%s: got secret=%t", f.name, got)
+		}
+	}
+	if shared.Secret {
+		t.Error("shared argument is marked")
+	}
+}
+
+func TestTxAnnotation(t *testing.T) {
+	id := UserArgument{PackageName: "PKG", ObjectName: "PEEK", ObjectID: 1, SubprogramID: 9,
+		ArgumentName: "P_ID", InOut: "IN", DataType: "NUMBER", PlsType: "NUMBER"}
+	if _, err := ParseTxMode("readonly,dirty"); err == nil {
+		t.Error("unknown mode accepted")
+	}
+	functions := ParseArgumentsIter(slices.Values([][]UserArgument{{id}}), nil)
+	functions = ApplyAnnotations(functions, []Annotation{
+		{Package: "PKG", Type: "tx", Name: "peek", Other: "serializable, readonly"},
+		{Package: "PKG", Type: "tx", Name: "peek", Other: "dirty"}, // ignored with a warning
+	})
+	f := functions[0]
+	if f.Tx != "readonly,serializable" || f.TxMode().Commits() {
+		t.Fatalf("got %q", f.Tx)
+	}
+	// the session-wide isolation level of a serializable transaction is reset by the next one
+	if opts := f.TxMode().TxOptions(); !opts.ReadOnly || opts.Isolation != sql.LevelSerializable {
+		t.Errorf("got %+v", opts)
+	}
+	if opts := (TxMode{}).TxOptions(); opts.Isolation != sql.LevelReadCommitted {
+		t.Errorf("default: got %+v", opts)
+	}
+	if _, callFun := f.PlsqlBlock(""); !strings.Contains(callFun, "s.Transactions.BeginTx(ctx, s.db, oracall.TxMode{ReadOnly: true, Serializable: true})") ||
+		!strings.Contains(callFun, "err = call.Rollback(tx)") || strings.Contains(callFun, "call.Commit(tx)") {
+		t.Errorf("got %s", callFun)
+	}
+	var buf strings.Builder
+	if err := SaveProtobuf(t.Context(), &buf, functions, "pkg", ""); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, `option (oracall.orasrv.tx) = "readonly,serializable";`) ||
+		strings.Contains(s, "service PkgTx") {
+		t.Errorf("got %s", s)
+	}
+
+	TxService = true
+	defer func() { TxService = false }()
+	buf.Reset()
+	if err := SaveProtobuf(t.Context(), &buf, functions, "pkg", ""); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, "service PkgTx {") ||
+		!strings.Contains(s, "rpc Begin (OracallTxBegin) returns (OracallTransaction) {}") {
+		t.Errorf("got %s", s)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// secretOption is the (oracall.orasrv.secret) field option, marking the fields of the --oracall:secret arguments.
+	secretOption       = "oracall.orasrv.secret"
+	secretOptionNumber = 13023
+
+	// Redacted replaces the secret strings.
+	Redacted = "***"
+)
+
+var redactedMessages sync.Map // protoreflect.MessageDescriptor -> bool
+
+// Redact returns m as is if it has no (oracall.orasrv.secret) fields,
+// or a copy with those replaced by Redacted (strings) or cleared - in the nested messages, too.
+func Redact(m proto.Message) proto.Message {
+	if m == nil {
+		return m
+	}
+	rm := m.ProtoReflect()
+	if !rm.IsValid() || !hasSecretField(rm.Descriptor(), nil) {
+		return m
+	}
+	m = proto.Clone(m)
+	redact(m.ProtoReflect())
+	return m
+}
+
+func redact(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if isSecret(fd) {
+			switch {
+			case fd.IsList() && fd.Kind() == protoreflect.StringKind:
+				for i, list := 0, v.List(); i < list.Len(); i++ {
+					list.Set(i, protoreflect.ValueOfString(Redacted))
+				}
+			case fd.Cardinality() != protoreflect.Repeated && fd.Kind() == protoreflect.StringKind:
+				m.Set(fd, protoreflect.ValueOfString(Redacted))
+			default:
+				m.Clear(fd)
+			}
+			return true
+		}
+		if md := fd.Message(); md == nil || !hasSecretField(md, nil) {
+			return true
+		}
+		switch {
+		case fd.IsList():
+			for i, list := 0, v.List(); i < list.Len(); i++ {
+				redact(list.Get(i).Message())
+			}
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				v.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+					redact(v.Message())
+					return true
+				})
+			}
+		default:
+			redact(v.Message())
+		}
+		return true
+	})
+}
+
+// hasSecretField reports whether the message, or any message of its fields, has a secret field.
+func hasSecretField(md protoreflect.MessageDescriptor, seen map[protoreflect.FullName]bool) bool {
+	if v, ok := redactedMessages.Load(md); ok {
+		return v.(bool)
+	}
+	if seen[md.FullName()] {
+		return false
+	}
+	if seen == nil {
+		seen = make(map[protoreflect.FullName]bool)
+	}
+	seen[md.FullName()] = true
+	var has bool
+	fields := md.Fields()
+	for i := 0; i < fields.Len() && !has; i++ {
+		fd := fields.Get(i)
+		md := fd.Message()
+		if fd.IsMap() {
+			md = fd.MapValue().Message()
+		}
+		has = isSecret(fd) || md != nil && hasSecretField(md, seen)
+	}
+	redactedMessages.Store(md, has)
+	return has
+}
+
+// isSecret reports whether the field has the (oracall.orasrv.secret) option set,
+// without importing its Go type (the option may be unknown).
+func isSecret(fd protoreflect.FieldDescriptor) bool {
+	opts := fd.Options()
+	if opts == nil {
+		return false
+	}
+	rm := opts.ProtoReflect()
+	var secret bool
+	rm.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.FullName() == secretOption {
+			secret = v.Bool()
+			return false
+		}
+		return true
+	})
+	if secret {
+		return true
+	}
+	for b := rm.GetUnknown(); len(b) != 0; {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return false
+		}
+		b = b[n:]
+		if num == secretOptionNumber && typ == protowire.VarintType {
+			v, n := protowire.ConsumeVarint(b)
+			return n > 0 && v != 0
+		}
+		if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+			return false
+		}
+		b = b[n:]
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestRedact(t *testing.T) {
+	// the (oracall.orasrv.secret) option as an unknown field, as without importing orasrv
+	secret := func() *descriptorpb.FieldOptions {
+		opts := new(descriptorpb.FieldOptions)
+		opts.ProtoReflect().SetUnknown(protowire.AppendVarint(protowire.AppendTag(nil, secretOptionNumber, protowire.VarintType), 1))
+		return opts
+	}
+	field := func(name string, num int32, label descriptorpb.FieldDescriptorProto_Label, typ descriptorpb.FieldDescriptorProto_Type, typeName string, opts *descriptorpb.FieldOptions) *descriptorpb.FieldDescriptorProto {
+		fd := &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(num),
+			Label: label.Enum(), Type: typ.Enum(), Options: opts}
+		if typeName != "" {
+			fd.TypeName = proto.String(typeName)
+		}
+		return fd
+	}
+	const (
+		optional = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+		repeated = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+		str      = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		msg      = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	)
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name: proto.String("redact_test.proto"), Package: proto.String("pkg"), Syntax: proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Cred"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("token", 1, optional, str, "", secret()),
+				field("pins", 2, repeated, str, "", secret()),
+				field("kind", 3, optional, str, "", nil),
+			}},
+			{Name: proto.String("Note"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("text", 1, optional, str, "", nil),
+			}},
+			{Name: proto.String("Login"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("user", 1, optional, str, "", nil),
+				field("passw", 2, optional, str, "", secret()),
+				field("creds", 3, repeated, msg, ".pkg.Cred", nil),
+				field("by_name", 4, repeated, msg, ".pkg.Login.ByNameEntry", nil),
+			}, NestedType: []*descriptorpb.DescriptorProto{
+				{Name: proto.String("ByNameEntry"), Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("key", 1, optional, str, "", nil),
+						field("value", 2, optional, msg, ".pkg.Cred", nil),
+					}},
+			}},
+		},
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credDesc, noteDesc, loginDesc := fd.Messages().Get(0), fd.Messages().Get(1), fd.Messages().Get(2)
+	newCred := func() protoreflect.Message {
+		cred := dynamicpb.NewMessage(credDesc)
+		cred.Set(credDesc.Fields().ByName("token"), protoreflect.ValueOfString("t0ken"))
+		pins := cred.Mutable(credDesc.Fields().ByName("pins")).List()
+		pins.Append(protoreflect.ValueOfString("1234"))
+		cred.Set(credDesc.Fields().ByName("kind"), protoreflect.ValueOfString("otp"))
+		return cred
+	}
+	login := dynamicpb.NewMessage(loginDesc)
+	F := loginDesc.Fields().ByName
+	login.Set(F("user"), protoreflect.ValueOfString("scott"))
+	login.Set(F("passw"), protoreflect.ValueOfString("tiger"))
+	login.Mutable(F("creds")).List().Append(protoreflect.ValueOfMessage(newCred()))
+	login.Mutable(F("by_name")).Map().Set(protoreflect.ValueOfString("a").MapKey(), protoreflect.ValueOfMessage(newCred()))
+
+	got := Redact(login).ProtoReflect()
+	if s := got.Get(F("user")).String(); s != "scott" {
+		t.Errorf("user: got %q", s)
+	}
+	if s := got.Get(F("passw")).String(); s != Redacted {
+		t.Errorf("passw: got %q", s)
+	}
+	for _, cred := range []protoreflect.Message{
+		got.Get(F("creds")).List().Get(0).Message(),
+		got.Get(F("by_name")).Map().Get(protoreflect.ValueOfString("a").MapKey()).Message(),
+	} {
+		C := credDesc.Fields().ByName
+		if cred.Get(C("token")).String() != Redacted || cred.Get(C("pins")).List().Get(0).String() != Redacted ||
+			cred.Get(C("kind")).String() != "otp" {
+			t.Errorf("got %v", cred)
+		}
+	}
+	if s := login.Get(F("passw")).String(); s != "tiger" {
+		t.Errorf("original changed: %q", s)
+	}
+
+	note := dynamicpb.NewMessage(noteDesc)
+	if m := Redact(note); m != proto.Message(note) {
+		t.Error("no secrets, but copied")
+	}
+}
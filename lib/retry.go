@@ -0,0 +1,81 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"context"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// RetryCodes are the transient ORA errors retried by default:
+// deadlock, discarded package state, failover and lost connection.
+var RetryCodes = []int{60, 3113, 3114, 4061, 4065, 4068, 25408}
+
+// RetryPolicy restarts the whole transaction of the idempotent functions on transient errors.
+//
+// Each attempt begins a new transaction; godror reports the broken sessions
+// (ORA-03113, ORA-25408) as driver.ErrBadConn, so database/sql discards them,
+// and the next attempt gets a fresh connection.
+// The calls joining a transaction (see JoinsTx) are not retried.
+type RetryPolicy struct {
+	// Codes are the retried ORA error codes, RetryCodes if empty.
+	Codes []int
+	// MaxAttempts limits the number of attempts, 3 if zero. 1 disables retrying.
+	MaxAttempts int
+	// Backoff is the wait before the first retry (100ms if zero),
+	// doubled with each retry, but at most MaxBackoff (5s if zero).
+	Backoff, MaxBackoff time.Duration
+}
+
+// Retryable reports whether err is one of the retried ORA errors.
+func (p RetryPolicy) Retryable(err error) bool {
+	code := oraCode(err)
+	if code == 0 {
+		return false
+	}
+	retryCodes := p.Codes
+	if len(retryCodes) == 0 {
+		retryCodes = RetryCodes
+	}
+	return slices.Contains(retryCodes, code)
+}
+
+// Do calls f till it succeeds, returns a not retryable error, or the attempts run out.
+//
+// maxAttempts overrides p.MaxAttempts, if positive.
+// The waits between the attempts are jittered, and cut short by the end of ctx.
+func (p RetryPolicy) Do(ctx context.Context, maxAttempts int, f func(context.Context) error) error {
+	if maxAttempts <= 0 {
+		if maxAttempts = p.MaxAttempts; maxAttempts <= 0 {
+			maxAttempts = 3
+		}
+	}
+	backoff, maxBackoff := p.Backoff, p.MaxBackoff
+	if backoff <= 0 {
+		backoff = 100 * time.Millisecond
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = 5 * time.Second
+	}
+	for attempt := 1; ; attempt++ {
+		err := f(ctx)
+		if err == nil || attempt >= maxAttempts || !p.Retryable(err) {
+			return err
+		}
+		if lgr := FromContext(ctx); lgr != nil {
+			lgr.Warn("retry", "attempt", attempt, "error", err)
+		}
+		timer := time.NewTimer(backoff/2 + rand.N(backoff/2+1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type codedError int
+
+func (e codedError) Error() string { return fmt.Sprintf("ORA-%05d", int(e)) }
+func (e codedError) Code() int     { return int(e) }
+
+func TestRetryPolicy(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+	for _, tc := range []struct {
+		err         error
+		maxAttempts int
+		want        int
+	}{
+		{err: nil, want: 1},
+		{err: codedError(60), want: 3},
+		{err: fmt.Errorf("wrapped: %w", NewQueryError("BEGIN NULL; END;", codedError(3113))), want: 3},
+		{err: codedError(25408), maxAttempts: 5, want: 5},
+		{err: codedError(4061), maxAttempts: 1, want: 1},
+		{err: codedError(1403), want: 1},
+		{err: errors.New("ORA-00060"), want: 1},
+	} {
+		var n int
+		err := p.Do(context.Background(), tc.maxAttempts, func(context.Context) error { n++; return tc.err })
+		if n != tc.want {
+			t.Errorf("%v: got %d attempts, wanted %d", tc.err, n, tc.want)
+		}
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%v: got %+v", tc.err, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var n int
+	err := RetryPolicy{Backoff: time.Hour}.Do(ctx, 0, func(context.Context) error { n++; cancel(); return codedError(60) })
+	if n != 1 || err == nil {
+		t.Errorf("canceled: got %d attempts, %+v", n, err)
+	}
+}
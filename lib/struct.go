@@ -5,7 +5,10 @@
 package oracall
 
 import (
+	"errors"
 	"fmt"
+	"path"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -27,14 +30,27 @@ const (
 )
 
 type Function struct {
-	LastDDL           time.Time `json:",omitzero"`
-	Replacement       *Function `json:",omitempty"`
-	Returns           *Argument `json:",omitempty"`
-	Package           string    `json:",omitzero"`
-	name, alias       string
-	Documentation     string     `json:",omitzero"`
-	Args              []Argument `json:",omitempty"`
-	Tag               []string   `json:",omitempty"`
+	LastDDL       time.Time `json:",omitzero"`
+	Replacement   *Function `json:",omitempty"`
+	Returns       *Argument `json:",omitempty"`
+	Package       string    `json:",omitzero"`
+	name, alias   string
+	overload      string
+	Documentation string     `json:",omitzero"`
+	Args          []Argument `json:",omitempty"`
+	// Results are the implicit result sets (DBMS_SQL.RETURN_RESULT), as OUT cursors.
+	Results []Argument `json:",omitempty"`
+	Tag     []string   `json:",omitempty"`
+	// Timeout bounds the call, if not zero.
+	Timeout time.Duration `json:",omitzero"`
+	// Tx is the transaction mode (see TxMode), empty for the default.
+	Tx string `json:",omitzero"`
+	// Errors map the ORA error codes to gRPC codes ("20001..20099=INVALID_ARGUMENT").
+	Errors []string `json:",omitempty"`
+	// Idempotent functions are called again on transient errors,
+	// at most MaxAttempts times (the server's RetryPolicy if zero).
+	Idempotent        bool `json:",omitzero"`
+	MaxAttempts       int  `json:",omitzero"`
 	handle            []string
 	maxTableSize      int
 	ReplacementIsJSON bool `json:",omitzero"`
@@ -45,6 +61,9 @@ func (f Function) MarshalJSONTo(enc *jsontext.Encoder) error {
 	enc.WriteToken(jsontext.BeginObject)
 	W("Package", f.Package)
 	W("Name", f.name)
+	if f.overload != "" {
+		W("Overload", f.overload)
+	}
 	if f.alias != "" {
 		W("Alias", f.alias)
 	}
@@ -63,6 +82,9 @@ func (f Function) MarshalJSONTo(enc *jsontext.Encoder) error {
 		W("Documentation", f.Documentation)
 	}
 	W("Args", f.Args)
+	if len(f.Results) != 0 {
+		W("Results", f.Results)
+	}
 	if len(f.Tag) != 0 {
 		W("Tag", f.Tag)
 	}
@@ -72,19 +94,55 @@ func (f Function) MarshalJSONTo(enc *jsontext.Encoder) error {
 	if f.maxTableSize != 0 {
 		W("MaxTableSize", f.maxTableSize)
 	}
+	if f.Timeout != 0 {
+		W("Timeout", f.Timeout.String())
+	}
+	if len(f.Errors) != 0 {
+		W("Errors", f.Errors)
+	}
+	if f.Idempotent {
+		W("Idempotent", true)
+	}
+	if f.MaxAttempts != 0 {
+		W("MaxAttempts", f.MaxAttempts)
+	}
+	if f.Tx != "" {
+		W("Tx", f.Tx)
+	}
 	return enc.WriteToken(jsontext.EndObject)
 }
 
+// lastDDL returns LastDDL in RFC3339, or empty if unknown.
+func (f Function) lastDDL() string {
+	if f.LastDDL.IsZero() {
+		return ""
+	}
+	return f.LastDDL.UTC().Format(time.RFC3339)
+}
+
 func (f Function) Name() string {
-	nm := strings.ToLower(f.name)
+	nm := strings.ToLower(f.baseName())
 	if f.alias != "" {
-		nm = strings.ToLower(f.name)
+		nm = strings.ToLower(f.baseName())
 	}
 	if f.Package == "" {
 		return nm
 	}
 	return UnoCap(f.Package) + "." + nm
 }
+
+// baseName returns the name of the function, suffixed with the overload number for overloaded functions.
+// The call itself uses the real name, and named notation, so the DB chooses the overload.
+func (f Function) baseName() string {
+	if f.overload == "" {
+		return f.name
+	}
+	return f.name + "_" + f.overload
+}
+
+// Overload returns the overload number of the function, or the empty string if it is not overloaded.
+func (f Function) Overload() string { return f.overload }
+
 func (f Function) RealName() string {
 	if f.Replacement != nil {
 		return f.Replacement.RealName()
@@ -109,18 +167,75 @@ func (f Function) String() string {
 }
 
 func (f Function) HasCursorOut() bool {
+	if len(f.Results) != 0 {
+		return true
+	}
 	if f.Returns != nil &&
 		f.Returns.IsOutput() && f.Returns.Type == "REF CURSOR" {
 		return true
 	}
+	if f.Returns != nil && f.Returns.IsStreamed() {
+		return true
+	}
 	for _, arg := range f.Args {
-		if arg.IsOutput() && arg.Type == "REF CURSOR" {
+		if arg.IsOutput() && (arg.Type == "REF CURSOR" || arg.IsStreamed()) {
 			return true
 		}
 	}
 	return false
 }
 
+// checkResults returns an error if the function has both implicit results and OUT arguments (or a return value):
+// godror returns the implicit results only from a query, which does not fill the OUT binds.
+func (f Function) checkResults() error {
+	if len(f.Results) != 0 && (f.Returns != nil || slices.ContainsFunc(f.Args, Argument.IsOutput)) {
+		return errors.New("implicit results with OUT arguments or a return value are not supported")
+	}
+	return nil
+}
+
+// Retryable reports whether the whole call can be repeated:
+// the function is idempotent, and nothing has been sent or received in chunks.
+func (f Function) Retryable() bool {
+	return f.Idempotent && !f.HasCursorOut() && !slices.ContainsFunc(f.Args, func(a Argument) bool { return a.Upload })
+}
+
+// TxMode returns the parsed transaction mode.
+func (f Function) TxMode() TxMode {
+	m, _ := ParseTxMode(f.Tx)
+	return m
+}
+
+// hasSecret reports whether any argument (or a field of its records) is secret.
+func (f Function) hasSecret() bool {
+	if f.Returns != nil && f.Returns.hasSecret() {
+		return true
+	}
+	return slices.ContainsFunc(f.Args, Argument.hasSecret)
+}
+
+// uploads returns the LOB inputs which can be uploaded in chunks, with a client-streaming variant of the call.
+func (f Function) uploads() []Argument {
+	if f.HasCursorOut() {
+		return nil
+	}
+	var args []Argument
+	for _, arg := range f.Args {
+		if arg.Upload {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+// hasStreamedLob reports whether any LOB output is streamed in chunks.
+func (f Function) hasStreamedLob() bool {
+	if f.Returns != nil && f.Returns.IsStreamed() {
+		return true
+	}
+	return slices.ContainsFunc(f.Args, Argument.IsStreamed)
+}
+
 type direction uint8
 
 func (dir direction) IsInput() bool  { return dir&DIR_IN > 0 }
@@ -187,6 +302,10 @@ type Argument struct {
 	Direction  direction `json:",omitzero"`
 	Precision  uint8     `json:",omitzero"`
 	Scale      uint8     `json:",omitzero"`
+	Defaulted  bool      `json:",omitzero"` // the parameter has a DEFAULT value
+	ChunkSize  int       `json:",omitzero"` // stream the LOB in chunks of this size
+	Upload     bool      `json:",omitzero"` // the LOB input can be uploaded in chunks
+	Secret     bool      `json:",omitzero"` // the value is redacted in the logs
 }
 
 type NamedArgument struct {
@@ -285,6 +404,60 @@ func (a Argument) IsNestedTable() bool {
 	return false
 }
 
+// IsMap reports whether the argument is an associative array indexed by strings (INDEX BY VARCHAR2),
+// which is a map<string, T> in protobuf.
+func (a Argument) IsMap() bool {
+	if a.Flavor != FLAVOR_TABLE || a.TableOf == nil {
+		return false
+	}
+	switch typ, _, _ := strings.Cut(strings.ToUpper(a.IndexBy), "("); typ {
+	case "VARCHAR2", "VARCHAR", "STRING", "LONG":
+		return true
+	}
+	return false
+}
+
+// IsOptional reports whether the argument can be left out of the call, to let its DEFAULT value apply.
+// Tables are always passed, as protobuf repeated fields have no presence.
+func (a Argument) IsOptional() bool {
+	return a.Defaulted && a.Direction == DIR_IN && a.Flavor != FLAVOR_TABLE && !a.Upload
+}
+
+// IsStreamed reports whether the argument is a LOB output, sent in chunks.
+func (a Argument) IsStreamed() bool {
+	return a.ChunkSize > 0 && a.IsOutput() && (a.Type == "CLOB" || a.Type == "BLOB")
+}
+
+// hasSecret reports whether the argument, or a field of its records, is secret.
+func (a Argument) hasSecret() bool {
+	if a.Secret || a.TableOf != nil && a.TableOf.hasSecret() {
+		return true
+	}
+	return slices.ContainsFunc(a.RecordOf, func(f NamedArgument) bool { return f.Argument != nil && f.Argument.hasSecret() })
+}
+
+// markSecret marks the argument, and the fields of its records, whose name matches the pattern (% is a wildcard).
+//
+// The nested arguments are copied before marking, as they may be shared with other arguments and functions.
+func (a *Argument) markSecret(pattern string) {
+	if ok, _ := path.Match(strings.ReplaceAll(pattern, "%", "*"), strings.ToLower(a.Name)); ok {
+		a.Secret = true
+	}
+	if a.TableOf != nil {
+		elem := *a.TableOf
+		elem.markSecret(pattern)
+		a.TableOf = &elem
+	}
+	a.RecordOf = slices.Clone(a.RecordOf)
+	for i, f := range a.RecordOf {
+		if f.Argument != nil {
+			field := *f.Argument
+			field.markSecret(pattern)
+			a.RecordOf[i].Argument = &field
+		}
+	}
+}
+
 func NewArgument(name, dataType, plsType, typeName, dirName string, dir direction,
 	charset, indexBy string, precision, scale uint8, charlength uint) Argument {
 
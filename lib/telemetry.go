@@ -0,0 +1,122 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name of the OpenTelemetry tracer and meter of the calls.
+const InstrumentationName = "github.com/tgulacsi/oracall"
+
+type callMetrics struct {
+	duration metric.Float64Histogram
+	rows     metric.Int64Histogram
+	errors   metric.Int64Counter
+}
+
+// getCallMetrics returns the instruments, created with the global MeterProvider,
+// which delegates to the provider set later with otel.SetMeterProvider.
+var getCallMetrics = sync.OnceValue(func() callMetrics {
+	meter := otel.Meter(InstrumentationName)
+	var m callMetrics
+	m.duration, _ = meter.Float64Histogram("oracall.call.duration",
+		metric.WithUnit("s"), metric.WithDescription("Duration of the function calls."))
+	m.rows, _ = meter.Int64Histogram("oracall.call.rows",
+		metric.WithUnit("{row}"), metric.WithDescription("Rows returned by the cursors of the calls."))
+	m.errors, _ = meter.Int64Counter("oracall.call.errors",
+		metric.WithUnit("{error}"), metric.WithDescription("Failed function calls."))
+	return m
+})
+
+// Call is the telemetry of one function call: its span, and the metrics recorded at End.
+type Call struct {
+	ctx   context.Context
+	span  trace.Span
+	start time.Time
+	attrs []attribute.KeyValue
+	rows  int64
+}
+
+// StartCall starts the span of the call of funName (PKG.FUNC), with the global TracerProvider.
+//
+// lastDDL is the time of the last DDL of the function, in RFC3339, or empty.
+func StartCall(ctx context.Context, funName, lastDDL string) (context.Context, *Call) {
+	pkg, fun, _ := strings.Cut(funName, ".")
+	c := Call{start: time.Now(), attrs: []attribute.KeyValue{
+		attribute.String("oracall.package", pkg),
+		attribute.String("oracall.function", fun),
+	}}
+	opts := []trace.SpanStartOption{trace.WithAttributes(c.attrs...)}
+	if lastDDL != "" {
+		opts = append(opts, trace.WithAttributes(attribute.String("oracall.last_ddl", lastDDL)))
+	}
+	c.ctx, c.span = otel.Tracer(InstrumentationName).Start(ctx, funName, opts...)
+	return c.ctx, &c
+}
+
+// Phase starts a child span of the call (prepare, exec, iterate, commit),
+// and returns the function ending it - only its first call counts.
+func (c *Call) Phase(name string) func(error) {
+	_, span := c.span.TracerProvider().Tracer(InstrumentationName).Start(c.ctx, name)
+	var done bool
+	return func(err error) {
+		if !done {
+			done = true
+			endSpan(span, err)
+		}
+	}
+}
+
+// AddRows adds n to the number of the returned rows.
+func (c *Call) AddRows(n int) { c.rows += int64(n) }
+
+// Commit commits tx in a "commit" phase.
+func (c *Call) Commit(tx interface{ Commit() error }) error {
+	end := c.Phase("commit")
+	err := tx.Commit()
+	end(err)
+	return err
+}
+
+// Rollback rolls back the transaction, which must not be committed (readonly, nocommit), in a "rollback" phase.
+func (c *Call) Rollback(tx interface{ Rollback() error }) error {
+	end := c.Phase("rollback")
+	err := tx.Rollback()
+	end(err)
+	return err
+}
+
+// End ends the span of the call, and records its duration, rows and error.
+func (c *Call) End(err error) {
+	endSpan(c.span, err)
+	m := getCallMetrics()
+	attrs := append(c.attrs, attribute.Bool("error", err != nil))
+	m.duration.Record(c.ctx, time.Since(c.start).Seconds(), metric.WithAttributes(attrs...))
+	m.rows.Record(c.ctx, c.rows, metric.WithAttributes(c.attrs...))
+	if err != nil {
+		m.errors.Add(c.ctx, 1, metric.WithAttributes(append(c.attrs, attribute.Int("oracall.ora_code", oraCode(err)))...))
+	}
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		if code := oraCode(err); code != 0 {
+			span.SetAttributes(attribute.Int("oracall.ora_code", code))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,89 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type fakeTx struct{ err error }
+
+func (tx fakeTx) Commit() error { return tx.err }
+
+func TestCallTelemetry(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	otel.SetTracerProvider(tp)
+	otel.SetMeterProvider(mp)
+
+	_, call := StartCall(context.Background(), "PKG.GET_ROWS", "2026-01-02T03:04:05Z")
+	call.Phase("exec")(nil)
+	endIterate := call.Phase("iterate")
+	call.AddRows(3)
+	call.AddRows(2)
+	endIterate(nil)
+	err := NewQueryError("BEGIN NULL; END;", codedError(1403))
+	if got := call.Commit(fakeTx{err: err}); got != err {
+		t.Errorf("commit: got %+v", got)
+	}
+	call.End(err)
+
+	spans := exp.GetSpans()
+	if len(spans) != 4 {
+		t.Fatalf("got %d spans: %+v", len(spans), spans)
+	}
+	byName := make(map[string]tracetest.SpanStub, len(spans))
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+	root := byName["PKG.GET_ROWS"]
+	want := map[attribute.Key]attribute.Value{
+		"oracall.package": attribute.StringValue("PKG"), "oracall.function": attribute.StringValue("GET_ROWS"),
+		"oracall.last_ddl": attribute.StringValue("2026-01-02T03:04:05Z"), "oracall.ora_code": attribute.IntValue(1403),
+	}
+	for _, kv := range root.Attributes {
+		if w, ok := want[kv.Key]; ok && w == kv.Value {
+			delete(want, kv.Key)
+		}
+	}
+	if len(want) != 0 {
+		t.Errorf("missing attributes %v from %v", want, root.Attributes)
+	}
+	for _, nm := range []string{"exec", "iterate", "commit"} {
+		if s, ok := byName[nm]; !ok || s.Parent.SpanID() != root.SpanContext.SpanID() {
+			t.Errorf("%s: got %+v", nm, s)
+		}
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]metricdata.Aggregation)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			got[m.Name] = m.Data
+		}
+	}
+	if h, ok := got["oracall.call.rows"].(metricdata.Histogram[int64]); !ok || len(h.DataPoints) != 1 || h.DataPoints[0].Sum != 5 {
+		t.Errorf("rows: got %+v", got["oracall.call.rows"])
+	}
+	if h, ok := got["oracall.call.duration"].(metricdata.Histogram[float64]); !ok || len(h.DataPoints) != 1 {
+		t.Errorf("duration: got %+v", got["oracall.call.duration"])
+	}
+	if s, ok := got["oracall.call.errors"].(metricdata.Sum[int64]); !ok || len(s.DataPoints) != 1 || s.DataPoints[0].Value != 1 {
+		t.Errorf("errors: got %+v", got["oracall.call.errors"])
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// TxMetadataKey is the gRPC metadata key of the transaction handle the calls join.
+const TxMetadataKey = "x-oracall-tx"
+
+const (
+	DefaultTxIdleTimeout = time.Minute
+	MaxTxIdleTimeout     = 10 * time.Minute
+)
+
+// ErrTxNotFound is returned for unknown, expired or somebody else's transaction handles.
+var ErrTxNotFound = errors.New("transaction not found")
+
+// Transactions holds the transactions spanning several calls,
+// begun by Begin and ended by Commit, Rollback, or being idle for longer than their idle timeout.
+//
+// The zero value is usable.
+type Transactions struct {
+	mu  sync.Mutex
+	txs map[string]*sharedTx
+}
+
+type sharedTx struct {
+	tx       *sql.Tx
+	end      func()
+	identity Identity
+	idle     time.Duration
+	timer    *time.Timer
+	// sem is held by the call using the transaction; lastUsed and done are guarded by it.
+	sem      chan struct{}
+	lastUsed time.Time
+	done     bool
+}
+
+// Tx is the transaction of a call: its own, or a joined one, which its Commit and Rollback leave open.
+type Tx struct {
+	*sql.Tx
+	joined bool
+	// noCommit transactions are rolled back by Commit (dry runs)
+	noCommit bool
+}
+
+// Commit commits the own transaction of the call - or rolls it back, in a dry run.
+func (tx Tx) Commit() error {
+	if tx.joined {
+		return nil
+	}
+	if tx.noCommit {
+		return tx.Tx.Rollback()
+	}
+	return tx.Tx.Commit()
+}
+
+// Rollback rolls back the own transaction of the call.
+func (tx Tx) Rollback() error {
+	if tx.joined {
+		return nil
+	}
+	return tx.Tx.Rollback()
+}
+
+// Joined reports whether the transaction is a joined one.
+func (tx Tx) Joined() bool { return tx.joined }
+
+// BeginTx begins the transaction of a call, as BeginTx does,
+// or joins the transaction named by the TxMetadataKey of the incoming metadata.
+// The transaction of a dry run (see IsDryRun) is never committed, and cannot be joined.
+//
+// ts may be nil, then there is nothing to join.
+// The returned function must be called at the end of the call.
+func (ts *Transactions) BeginTx(ctx context.Context, db *sql.DB, mode TxMode) (Tx, func(), error) {
+	mode.NoCommit = mode.NoCommit || IsDryRun(ctx)
+	id := txIDFromContext(ctx)
+	if id == "" {
+		tx, end, err := BeginTx(ctx, db, mode.TxOptions())
+		return Tx{Tx: tx, noCommit: mode.NoCommit}, end, err
+	}
+	if ts == nil {
+		return Tx{}, nil, fmt.Errorf("%s: %w", id, ErrTxNotFound)
+	}
+	if mode.NoCommit {
+		return Tx{}, nil, fmt.Errorf("%w: a nocommit call or dry run cannot join a transaction", ErrInvalidArgument)
+	}
+	t, err := ts.acquire(ctx, id)
+	if err != nil {
+		return Tx{}, nil, err
+	}
+	return Tx{Tx: t.tx, joined: true}, t.release, nil
+}
+
+// Begin begins a transaction, as BeginTx does, to be joined by the calls having its handle in their metadata.
+// The transaction is rolled back after being idle for the idle timeout (DefaultTxIdleTimeout if zero, at most MaxTxIdleTimeout).
+//
+// It returns the handle of the transaction, and the effective idle timeout.
+func (ts *Transactions) Begin(ctx context.Context, db *sql.DB, idle time.Duration, mode TxMode) (string, time.Duration, error) {
+	if ts == nil {
+		return "", 0, fmt.Errorf("transactions: %w", ErrUnsupported)
+	}
+	if mode.NoCommit || IsDryRun(ctx) {
+		return "", 0, fmt.Errorf("%w: nocommit transaction", ErrInvalidArgument)
+	}
+	if idle <= 0 {
+		idle = DefaultTxIdleTimeout
+	} else if idle > MaxTxIdleTimeout {
+		idle = MaxTxIdleTimeout
+	}
+	// the transaction outlives the call beginning it
+	txCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	tx, end, err := BeginTx(txCtx, db, mode.TxOptions())
+	if err != nil {
+		cancel()
+		return "", 0, err
+	}
+	identity, _ := IdentityFromContext(ctx)
+	t := &sharedTx{
+		tx: tx, end: func() { end(); cancel() }, identity: identity, idle: idle,
+		sem: make(chan struct{}, 1), lastUsed: time.Now(),
+	}
+	id := rand.Text()
+	t.timer = time.AfterFunc(idle, func() { ts.expire(id, t) })
+	ts.mu.Lock()
+	if ts.txs == nil {
+		ts.txs = make(map[string]*sharedTx)
+	}
+	ts.txs[id] = t
+	ts.mu.Unlock()
+	return id, idle, nil
+}
+
+// Commit commits the transaction of the handle.
+func (ts *Transactions) Commit(ctx context.Context, id string) error {
+	t, err := ts.acquire(ctx, id)
+	if err != nil {
+		return err
+	}
+	return ts.finish(id, t, true)
+}
+
+// Rollback rolls back the transaction of the handle.
+func (ts *Transactions) Rollback(ctx context.Context, id string) error {
+	t, err := ts.acquire(ctx, id)
+	if err != nil {
+		return err
+	}
+	return ts.finish(id, t, false)
+}
+
+// acquire waits for the transaction to be unused, and holds it for the caller - the same Identity which has begun it.
+func (ts *Transactions) acquire(ctx context.Context, id string) (*sharedTx, error) {
+	var t *sharedTx
+	if ts != nil {
+		ts.mu.Lock()
+		t = ts.txs[id]
+		ts.mu.Unlock()
+	}
+	if identity, _ := IdentityFromContext(ctx); t == nil || t.identity != identity {
+		return nil, fmt.Errorf("%s: %w", id, ErrTxNotFound)
+	}
+	select {
+	case t.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	t.timer.Stop()
+	if t.done {
+		<-t.sem
+		return nil, fmt.Errorf("%s: %w", id, ErrTxNotFound)
+	}
+	return t, nil
+}
+
+// release restarts the idle timer, and lets the next call use the transaction.
+func (t *sharedTx) release() {
+	t.lastUsed = time.Now()
+	t.timer.Reset(t.idle)
+	<-t.sem
+}
+
+// finish ends the held transaction.
+func (ts *Transactions) finish(id string, t *sharedTx, commit bool) error {
+	ts.mu.Lock()
+	delete(ts.txs, id)
+	ts.mu.Unlock()
+	t.done = true
+	var err error
+	if commit {
+		err = t.tx.Commit()
+	} else {
+		err = t.tx.Rollback()
+	}
+	t.end()
+	<-t.sem
+	return err
+}
+
+// expire rolls back the transaction if it is still idle (it may have been used since the timer fired).
+func (ts *Transactions) expire(id string, t *sharedTx) {
+	t.sem <- struct{}{}
+	if t.done || time.Since(t.lastUsed) < t.idle {
+		<-t.sem
+		return
+	}
+	ts.finish(id, t, false)
+}
+
+// JoinsTx reports whether the calls of the context join a transaction (by the TxMetadataKey of the incoming metadata).
+//
+// Such calls are not retried: an attempt is not a whole transaction.
+func JoinsTx(ctx context.Context) bool { return txIDFromContext(ctx) != "" }
+
+// txIDFromContext returns the transaction handle of the incoming metadata.
+func txIDFromContext(ctx context.Context) string {
+	if vv := metadata.ValueFromIncomingContext(ctx, TxMetadataKey); len(vv) != 0 {
+		return vv[0]
+	}
+	return ""
+}
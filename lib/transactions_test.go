@@ -0,0 +1,141 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// txConnector connects to a fake DB, counting the commits and rollbacks.
+type txConnector struct{ commits, rollbacks *atomic.Int32 }
+
+func (tc txConnector) Connect(context.Context) (driver.Conn, error) { return txConn(tc), nil }
+func (tc txConnector) Driver() driver.Driver                        { return nil }
+
+type txConn txConnector
+
+func (c txConn) Prepare(string) (driver.Stmt, error) { return nil, errors.ErrUnsupported }
+func (c txConn) Close() error                        { return nil }
+func (c txConn) Begin() (driver.Tx, error)           { return c, nil }
+func (c txConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return c, nil
+}
+func (c txConn) Commit() error   { c.commits.Add(1); return nil }
+func (c txConn) Rollback() error { c.rollbacks.Add(1); return nil }
+
+func TestTransactions(t *testing.T) {
+	var commits, rollbacks atomic.Int32
+	db := sql.OpenDB(txConnector{commits: &commits, rollbacks: &rollbacks})
+	defer db.Close()
+	ctx := context.Background()
+	var ts Transactions
+
+	if _, idle, err := ts.Begin(ctx, db, time.Hour, TxMode{NoCommit: true}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("nocommit: got %v, %+v", idle, err)
+	}
+	id, idle, err := ts.Begin(ctx, db, time.Hour, TxMode{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idle != MaxTxIdleTimeout {
+		t.Errorf("idle: got %v", idle)
+	}
+	joinCtx := metadata.NewIncomingContext(ctx, metadata.Pairs(TxMetadataKey, id))
+	if !JoinsTx(joinCtx) || JoinsTx(ctx) {
+		t.Error("JoinsTx")
+	}
+	for range 2 {
+		tx, end, err := ts.BeginTx(joinCtx, db, TxMode{ReadOnly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tx.Joined() || tx.Commit() != nil || tx.Rollback() != nil {
+			t.Errorf("got %+v", tx)
+		}
+		end()
+	}
+	if commits.Load() != 0 || rollbacks.Load() != 0 {
+		t.Errorf("joined calls ended the transaction: %d commits, %d rollbacks", commits.Load(), rollbacks.Load())
+	}
+	if _, _, err := ts.BeginTx(joinCtx, db, TxMode{NoCommit: true}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("nocommit join: got %+v", err)
+	}
+	if _, _, err := ts.BeginTx(ContextWithIdentity(joinCtx, Identity{User: "other"}), db, TxMode{}); !errors.Is(err, ErrTxNotFound) {
+		t.Errorf("other identity: got %+v", err)
+	}
+	if _, _, err := (*Transactions)(nil).BeginTx(joinCtx, db, TxMode{}); !errors.Is(err, ErrTxNotFound) {
+		t.Errorf("no transactions: got %+v", err)
+	}
+	if err := ts.Commit(ctx, id); err != nil || commits.Load() != 1 {
+		t.Errorf("commit: %d %+v", commits.Load(), err)
+	}
+	if err := ts.Rollback(ctx, id); !errors.Is(err, ErrTxNotFound) {
+		t.Errorf("rollback after commit: got %+v", err)
+	}
+
+	if id, _, err = ts.Begin(ctx, db, 10*time.Millisecond, TxMode{}); err != nil {
+		t.Fatal(err)
+	}
+	for start := time.Now(); rollbacks.Load() == 0 && time.Since(start) < time.Second; {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if rollbacks.Load() != 1 {
+		t.Errorf("idle transaction is not rolled back")
+	}
+	if err := ts.Commit(ctx, id); !errors.Is(err, ErrTxNotFound) {
+		t.Errorf("commit after expiry: got %+v", err)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	var commits, rollbacks atomic.Int32
+	db := sql.OpenDB(txConnector{commits: &commits, rollbacks: &rollbacks})
+	defer db.Close()
+	var ts Transactions
+	for _, ctx := range []context.Context{
+		ContextWithDryRun(context.Background()),
+		metadata.NewIncomingContext(context.Background(), metadata.Pairs(DryRunMetadataKey, "true")),
+	} {
+		if !IsDryRun(ctx) {
+			t.Fatal("not a dry run")
+		}
+		tx, end, err := ts.BeginTx(ctx, db, TxMode{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = tx.Commit()
+		end()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := ts.Begin(ctx, db, 0, TxMode{}); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("begin: got %+v", err)
+		}
+	}
+	if commits.Load() != 0 || rollbacks.Load() != 2 {
+		t.Errorf("got %d commits, %d rollbacks", commits.Load(), rollbacks.Load())
+	}
+	if IsDryRun(metadata.NewIncomingContext(context.Background(), metadata.Pairs(DryRunMetadataKey, "0"))) {
+		t.Error("dry run by 0")
+	}
+
+	id, _, err := ts.Begin(context.Background(), db, 0, TxMode{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Rollback(context.Background(), id)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(TxMetadataKey, id, DryRunMetadataKey, "1"))
+	if _, _, err := ts.BeginTx(ctx, db, TxMode{}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("join: got %+v", err)
+	}
+}
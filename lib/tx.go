@@ -0,0 +1,84 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oracall
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// TxMode is the transaction mode of a function, set by --oracall:tx fn=readonly|serializable|nocommit.
+type TxMode struct {
+	// ReadOnly transactions are never committed.
+	ReadOnly bool
+	// Serializable transactions use the SERIALIZABLE isolation level.
+	Serializable bool
+	// NoCommit transactions are always rolled back, as for simulations.
+	NoCommit bool
+}
+
+// ParseTxMode parses the comma separated transaction modes ("readonly", "serializable", "nocommit").
+func ParseTxMode(s string) (TxMode, error) {
+	var m TxMode
+	for mode := range strings.SplitSeq(s, ",") {
+		switch strings.ToLower(strings.TrimSpace(mode)) {
+		case "readonly":
+			m.ReadOnly = true
+		case "serializable":
+			m.Serializable = true
+		case "nocommit":
+			m.NoCommit = true
+		case "":
+		default:
+			return m, fmt.Errorf("unknown transaction mode %q (wanted readonly, serializable or nocommit)", mode)
+		}
+	}
+	return m, nil
+}
+
+func (m TxMode) String() string {
+	modes := make([]string, 0, 3)
+	if m.ReadOnly {
+		modes = append(modes, "readonly")
+	}
+	if m.Serializable {
+		modes = append(modes, "serializable")
+	}
+	if m.NoCommit {
+		modes = append(modes, "nocommit")
+	}
+	return strings.Join(modes, ",")
+}
+
+// TxOptions returns the options of the transaction.
+//
+// The isolation level is always explicit: godror sets it with ALTER SESSION, which outlives the transaction,
+// so a session used by a serializable transaction is set back to READ COMMITTED by the next one.
+func (m TxMode) TxOptions() *sql.TxOptions {
+	opts := sql.TxOptions{ReadOnly: m.ReadOnly, Isolation: sql.LevelReadCommitted}
+	if m.Serializable {
+		opts.Isolation = sql.LevelSerializable
+	}
+	return &opts
+}
+
+// Commits reports whether the transaction is committed on success.
+func (m TxMode) Commits() bool { return !m.ReadOnly && !m.NoCommit }
+
+// goString returns the Go source of m.
+func (m TxMode) goString() string {
+	fields := make([]string, 0, 3)
+	if m.ReadOnly {
+		fields = append(fields, "ReadOnly: true")
+	}
+	if m.Serializable {
+		fields = append(fields, "Serializable: true")
+	}
+	if m.NoCommit {
+		fields = append(fields, "NoCommit: true")
+	}
+	return "oracall.TxMode{" + strings.Join(fields, ", ") + "}"
+}
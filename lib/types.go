@@ -267,6 +267,50 @@ func (qe *QueryError) Line() string {
 	return qe.line
 }
 
+func (qe *QueryError) Query() string {
+	if qe == nil {
+		return ""
+	}
+	return qe.query
+}
+
+// ExceptionCodes are the ORA error codes of the predefined PL/SQL exceptions.
+var ExceptionCodes = map[string]int{
+	"ACCESS_INTO_NULL":        6530,
+	"CASE_NOT_FOUND":          6592,
+	"COLLECTION_IS_NULL":      6531,
+	"CURSOR_ALREADY_OPEN":     6511,
+	"DUP_VAL_ON_INDEX":        1,
+	"INVALID_CURSOR":          1001,
+	"INVALID_NUMBER":          1722,
+	"LOGIN_DENIED":            1017,
+	"NO_DATA_FOUND":           1403,
+	"NOT_LOGGED_ON":           1012,
+	"PROGRAM_ERROR":           6501,
+	"ROWTYPE_MISMATCH":        6504,
+	"SELF_IS_NULL":            30625,
+	"STORAGE_ERROR":           6500,
+	"SUBSCRIPT_BEYOND_COUNT":  6533,
+	"SUBSCRIPT_OUTSIDE_LIMIT": 6532,
+	"SYS_INVALID_ROWID":       1410,
+	"TIMEOUT_ON_RESOURCE":     51,
+	"TOO_MANY_ROWS":           1422,
+	"VALUE_ERROR":             6502,
+	"ZERO_DIVIDE":             1476,
+}
+
+// oraCode returns the (positive) ORA error code of err, or 0.
+func oraCode(err error) int {
+	var ec interface{ Code() int }
+	if err == nil || !errors.As(err, &ec) || ec == nil {
+		return 0
+	}
+	if code := ec.Code(); code < 0 {
+		return -code
+	}
+	return ec.Code()
+}
+
 // NewQueryError wraps the error, parsing the error line number if possible.
 func NewQueryError(qry string, err error) *QueryError {
 	if err == nil {
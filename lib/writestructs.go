@@ -25,6 +25,9 @@ import (
 var ErrMissingTableOf = errors.New("missing TableOf info")
 var ErrInvalidArgument = errors.New("invalid argument")
 
+// ErrUnsupported is returned for the functions that cannot be called without generated code.
+var ErrUnsupported = errors.New("unsupported")
+
 func SaveFunctions(ctx context.Context, dst io.Writer, functions []Function, pkg, pbImport string, saveStructs bool) error {
 	logger := zlog.SFromContext(ctx)
 	var err error
@@ -46,16 +49,17 @@ func SaveFunctions(ctx context.Context, dst io.Writer, functions []Function, pkg
 		if lastDDL.IsZero() {
 			lastDDL = time.Now()
 		}
-		var implement string
+		var implement, txImplement string
 		if !Gogo {
 			implement = "pb.Unimplemented" + pbPkg + "Server"
+			txImplement = "pb.Unimplemented" + pbPkg + "TxServer"
 		}
 		tagB.Reset()
 		for _, fun := range functions {
 			if len(fun.Tag) == 0 {
 				continue
 			}
-			fn := fun.name
+			fn := fun.baseName()
 			if fun.alias != "" {
 				fn = fun.alias
 			}
@@ -134,6 +138,12 @@ type oracallServer struct {
 	BeforeHook func(ctx context.Context, funName string, input interface { ProtoMessage() }) error
 	PrepareHook func(ctx context.Context, funName string, callText *string, params *[]interface{}) error
 	AfterHook func(ctx context.Context, funName string, params []interface{}, output interface { ProtoMessage() }) error
+	// RetryPolicy retries the idempotent functions on transient errors.
+	RetryPolicy oracall.RetryPolicy
+	// Transactions are begun by TxServer, and joined by the calls with their handle in the metadata.
+	Transactions *oracall.Transactions
+	// DbmsOutput captures the DBMS_OUTPUT of every call, not just of those asking for it in their metadata.
+	DbmsOutput bool
 
 	`+implement+`
 }
@@ -146,11 +156,47 @@ func NewServer(
 	return &oracallServer{
 		db: db, 
 		Logger: logger, DBLog: dbLog, 
+		Transactions: new(oracall.Transactions),
 	    `+tagMap+` 
 	}
 }
 
 `)
+		if TxService {
+			io.WriteString(w, `// TxServer returns the server of the `+pbPkg+`Tx service, beginning the transactions the calls of s can join.
+func (s *oracallServer) TxServer() *oracallTxServer { return &oracallTxServer{s: s} }
+
+type oracallTxServer struct {
+	s *oracallServer
+
+	`+txImplement+`
+}
+
+func (s *oracallTxServer) Begin(ctx context.Context, input *pb.OracallTxBegin) (*pb.OracallTransaction, error) {
+	id, idle, err := s.s.Transactions.Begin(ctx, s.s.db, time.Duration(input.GetIdleSeconds())*time.Second,
+		oracall.TxMode{ReadOnly: input.GetReadOnly(), Serializable: input.GetSerializable()})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.OracallTransaction{Id: id, IdleSeconds: uint32(idle / time.Second)}, nil
+}
+
+func (s *oracallTxServer) Commit(ctx context.Context, input *pb.OracallTransaction) (*pb.OracallTransaction, error) {
+	if err := s.s.Transactions.Commit(ctx, input.GetId()); err != nil {
+		return nil, err
+	}
+	return &pb.OracallTransaction{Id: input.GetId()}, nil
+}
+
+func (s *oracallTxServer) Rollback(ctx context.Context, input *pb.OracallTransaction) (*pb.OracallTransaction, error) {
+	if err := s.s.Transactions.Rollback(ctx, input.GetId()); err != nil {
+		return nil, err
+	}
+	return &pb.OracallTransaction{Id: input.GetId()}, nil
+}
+
+`)
+		}
 	}
 	types := make(map[string]string, 16)
 	inits := make([]string, 0, len(functions))
@@ -158,6 +204,9 @@ func NewServer(
 
 FunLoop:
 	for _, fun := range functions {
+		if err = fun.checkResults(); err != nil {
+			return fmt.Errorf("%s: %w", fun.Name(), err)
+		}
 		structW := io.Writer(w)
 		if !saveStructs {
 			structW = io.Discard
@@ -294,7 +343,7 @@ func TestCalls(t *testing.T) {
 `)
 	}
 	FN := func(f Function) string {
-		fn := f.name
+		fn := f.baseName()
 		if f.alias != "" {
 			fn = f.alias
 		}
@@ -348,7 +397,7 @@ var TestFunctions = map[string]func(t *testing.T, jsonText []byte) {
 }
 
 func (f Function) getPlsqlConstName() string {
-	nm := f.name
+	nm := f.baseName()
 	if f.alias != "" {
 		nm = f.alias
 	}
@@ -360,7 +409,7 @@ func (f Function) getStructName(out, withPackage bool) string {
 	if out {
 		dirname = "output"
 	}
-	nm := f.name
+	nm := f.baseName()
 	if f.alias != "" {
 		nm = f.alias
 	}
@@ -11,12 +11,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"net"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -28,11 +32,16 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
 	"github.com/google/renameio/v2"
 	"github.com/peterbourgon/ff/v4"
 	"github.com/peterbourgon/ff/v4/ffhelp"
+	"github.com/tgulacsi/oracall/auth"
 	custom "github.com/tgulacsi/oracall/custom"
+	"github.com/tgulacsi/oracall/dynamic"
 	oracall "github.com/tgulacsi/oracall/lib"
+	"github.com/tgulacsi/oracall/orasrv"
 	"github.com/tgulacsi/oracall/source"
 
 	// for Oracle-specific drivers
@@ -72,8 +81,13 @@ func Main() error {
 	flagExcept := FS.StringLong("except", "", "except these functions")
 	flagReplace := FS.StringLong("replace", "", "funcA=>funcB")
 	FS.IntVar(&oracall.MaxTableSize, 0, "max-table-size", oracall.MaxTableSize, "maximum table size for PL/SQL associative arrays")
+	FS.IntVar(&oracall.LobChunkSize, 0, "lob-chunk-size", oracall.LobChunkSize, "default chunk size of the streamed LOB outputs (--oracall:stream)")
 	FS.StringVar(&dsn, 0, "connect", "", "connect to DB for retrieving function arguments")
 	flagPkgCacheDir := FS.StringLong("pkg-cache-dir", "", "directory for per-package JSON cache files")
+	flagSrc := FS.StringLong("src", "", "comma-separated package specification files (.pck, .pks) to read the arguments from, instead of the DB")
+	flagSrcOwner := FS.StringLong("src-owner", "", "schema of the packages read with --src, if not qualified in the source")
+	FS.BoolVar(&oracall.TxService, 0, "tx-service", "add the <Pkg>Tx service of the transactions spanning several calls")
+	flagFieldLock := FS.BoolLongDefault("field-lock", true, "keep protobuf field numbers stable in a lock file next to the .proto")
 
 	var db *sql.DB
 
@@ -131,6 +145,17 @@ func Main() error {
 			case db != nil:
 				logger.Info("read from DB")
 				_, functions, annotations, err = parseDB(ctx, db, pattern, *flagDump, *flagPkgCacheDir, filter)
+			case *flagSrc != "":
+				logger.Info("read from", "src", *flagSrc)
+				if pattern != "%" {
+					rPattern := regexp.MustCompile("(?i)" + strings.NewReplacer(
+						".", "[.]", "%", ".*",
+					).Replace(pattern))
+					filters = append(filters, func(s string) bool {
+						return rPattern.MatchString(s)
+					})
+				}
+				_, functions, annotations, err = parseSources(ctx, strings.Split(*flagSrc, ","), *flagSrcOwner, *flagPkgCacheDir, filter)
 			case *flagPkgCacheDir != "":
 				logger.Info("read from", "cache", *flagPkgCacheDir)
 				// file -> memory: load from per-package cache files, no DB needed.
@@ -258,17 +283,31 @@ func Main() error {
 					// nosemgrep: go.lang.correctness.permissions.file_permission.incorrect-default-permission
 					_ = os.MkdirAll(filepath.Dir(pbFn), 0775)
 					logger.Info("Writing Protocol Buffers", "file", pbFn)
+					var lock *oracall.FieldLock
+					lockFn := oracall.FieldLockFileName(pbFn)
+					if *flagFieldLock {
+						var err error
+						if lock, err = oracall.ReadFieldLock(lockFn); err != nil {
+							return fmt.Errorf("read field lock %s: %w", lockFn, err)
+						}
+					}
 					fh, err := os.Create(pbFn)
 					if err != nil {
 						return fmt.Errorf("create proto: %w", err)
 					}
-					err = oracall.SaveProtobuf(ctx, fh, functions, pbPkg, pbPath)
+					err = oracall.SaveProtobufWithLock(ctx, fh, functions, pbPkg, pbPath, lock)
 					if closeErr := fh.Close(); closeErr != nil && err == nil {
 						err = closeErr
 					}
 					if err != nil {
 						return fmt.Errorf("SaveProtobuf: %w", err)
 					}
+					if lock != nil {
+						logger.Info("Writing field lock", "file", lockFn)
+						if err := lock.WriteFile(lockFn); err != nil {
+							return fmt.Errorf("write field lock %s: %w", lockFn, err)
+						}
+					}
 
 					args := append(make([]string, 0, 5),
 						"--proto_path="+*flagBaseDir+":.")
@@ -329,13 +368,15 @@ func Main() error {
 
 	FS = ff.NewFlagSet("update")
 	flagUpdatePkgCacheDir := FS.StringLong("pkg-cache-dir", "", "directory for per-package JSON cache files (required)")
+	flagUpdateSrc := FS.StringLong("src", "", "comma-separated package specification files (.pck, .pks) to read the arguments from, instead of the DB")
+	flagUpdateSrcOwner := FS.StringLong("src-owner", "", "schema of the packages read with --src, if not qualified in the source")
 	updateCmd := ff.Command{Name: "update", Flags: FS,
 		Exec: func(ctx context.Context, args []string) error {
 			if *flagUpdatePkgCacheDir == "" {
 				return errors.New("--pkg-cache-dir is required for update")
 			}
-			if db == nil {
-				return errors.New("--connect is required for update")
+			if db == nil && *flagUpdateSrc == "" {
+				return errors.New("--connect or --src is required for update")
 			}
 			pattern := "%"
 			if len(args) > 0 {
@@ -344,17 +385,236 @@ func Main() error {
 			if err := os.MkdirAll(*flagUpdatePkgCacheDir, 0775); err != nil {
 				return fmt.Errorf("mkdirAll %s: %w", *flagUpdatePkgCacheDir, err)
 			}
+			if db == nil {
+				_, _, _, err := parseSources(ctx, strings.Split(*flagUpdateSrc, ","), *flagUpdateSrcOwner, *flagUpdatePkgCacheDir, nil)
+				return err
+			}
 			// Connect to DB, write per-package cache files, discard generated functions.
 			_, _, _, err := parseDB(ctx, db, pattern, "", *flagUpdatePkgCacheDir, nil)
 			return err
 		},
 	}
 
+	FS = ff.NewFlagSet("diff")
+	flagDiffOld := FS.StringLong("old", "", "old package cache directory")
+	flagDiffNew := FS.StringLong("new", "", "new package cache directory")
+	flagDiffFormat := FS.StringLong("format", "text", "output format (text|json)")
+	diffCmd := ff.Command{Name: "diff", Flags: FS,
+		Exec: func(ctx context.Context, args []string) error {
+			if *flagDiffOld == "" || *flagDiffNew == "" {
+				return errors.New("--old and --new are required for diff")
+			}
+			var filter func(string) bool
+			if len(args) > 0 && args[0] != "%" {
+				rPattern := regexp.MustCompile("(?i)" + strings.NewReplacer(
+					".", "[.]", "%", ".*",
+				).Replace(args[0]))
+				filter = rPattern.MatchString
+			}
+			load := func(dir string) ([]oracall.Function, error) {
+				_, functions, annotations, err := oracall.ParsePackageCaches(ctx, dir, filter)
+				if err != nil {
+					return nil, fmt.Errorf("parse %s: %w", dir, err)
+				}
+				return oracall.ApplyAnnotations(functions, annotations), nil
+			}
+			oldFuncs, err := load(*flagDiffOld)
+			if err != nil {
+				return err
+			}
+			newFuncs, err := load(*flagDiffNew)
+			if err != nil {
+				return err
+			}
+			changes := oracall.DiffFunctions(oldFuncs, newFuncs)
+			switch *flagDiffFormat {
+			case "json":
+				err = json.MarshalWrite(os.Stdout, changes, jsontext.WithIndent("  "))
+				if err == nil {
+					_, err = os.Stdout.Write([]byte{'\n'})
+				}
+			case "text", "":
+				err = oracall.WriteChanges(os.Stdout, changes)
+			default:
+				return fmt.Errorf("unknown format %q (text|json)", *flagDiffFormat)
+			}
+			if err != nil {
+				return err
+			}
+			if oracall.HasBreaking(changes) {
+				return errors.New("breaking changes found")
+			}
+			return nil
+		},
+	}
+
+	FS = ff.NewFlagSet("serve")
+	flagServePkgCacheDir := FS.StringLong("pkg-cache-dir", "", "directory of the per-package JSON cache files to serve (required)")
+	flagServeListen := FS.StringLong("listen", "localhost:8080", "address to listen on")
+	flagServeReload := FS.DurationLong("reload", time.Minute, "check for changed packages this often (0 to disable)")
+	flagServeDrain := FS.DurationLong("drain", 30*time.Second, "wait this long for the running calls on shutdown")
+	flagServeMetrics := FS.StringLong("metrics", "", "address to serve the Prometheus metrics on, at /metrics")
+	flagServePolicy := FS.StringLong("policy", "", "JSON file of the roles allowed for the method tags")
+	flagServeRolesMetadata := FS.StringLong("roles-metadata", "", "metadata key of the caller's roles (only behind a trusted proxy!)")
+	flagServeUserMetadata := FS.StringLong("user-metadata", "", "metadata key of the database user to proxy the calls to (only behind a trusted proxy!)")
+	flagServeClientIDMetadata := FS.StringLong("client-id-metadata", "", "metadata key of the CLIENT_IDENTIFIER of the calls (only behind a trusted proxy!)")
+	flagServeJWKS := FS.StringLong("jwks", "", "JWKS file of the keys to verify the Bearer JWTs with")
+	flagServeJWTIssuer := FS.StringLong("jwt-issuer", "", "required issuer of the JWTs")
+	flagServeJWTAudience := FS.StringLong("jwt-audience", "", "required audience of the JWTs")
+	flagServeBasicLogin := FS.BoolLong("basic-login", "check the Basic credentials by logging in to the database")
+	flagServeDbmsOutput := FS.BoolLong("dbms-output", "return the DBMS_OUTPUT of every call in the trailer, not just when asked for")
+	FS.BoolVar(&oracall.TxService, 0, "tx-service", "serve the <Pkg>Tx services of the transactions spanning several calls")
+	flagServeErrorCodes := FS.StringLong("error-codes", "", "file of ORA error code to gRPC code mappings (20001..20099=INVALID_ARGUMENT), one per line")
+	flagServeDefaultErrorCodes := FS.BoolLong("default-error-codes", "map the common ORA errors to gRPC codes, after the --error-codes (see orasrv.DefaultErrorCodes)")
+	serveCmd := ff.Command{Name: "serve", Flags: FS,
+		Exec: func(ctx context.Context, args []string) error {
+			if *flagServePkgCacheDir == "" {
+				return errors.New("--pkg-cache-dir is required for serve")
+			}
+			if db == nil {
+				return errors.New("--connect is required for serve")
+			}
+			pattern := "%"
+			if len(args) > 0 {
+				pattern = args[0]
+			}
+			like := func(pattern string) *regexp.Regexp {
+				return regexp.MustCompile("(?i)^" + strings.NewReplacer(
+					".", "[.]", "%", ".*",
+				).Replace(pattern) + "$")
+			}
+			pkgPattern, _, _ := strings.Cut(pattern, ".")
+			rPkg, rFun := like(pkgPattern), like(pattern)
+			filter := func(s string) bool {
+				// packages are checked as "PKG."
+				if pkg, ok := strings.CutSuffix(s, "."); ok {
+					return rPkg.MatchString(pkg)
+				}
+				return rFun.MatchString(s)
+			}
+			if *flagServeErrorCodes != "" {
+				fh, err := os.Open(*flagServeErrorCodes)
+				if err != nil {
+					return err
+				}
+				ecs, err := orasrv.ReadErrorCodes(fh)
+				fh.Close()
+				if err != nil {
+					return fmt.Errorf("%s: %w", *flagServeErrorCodes, err)
+				}
+				orasrv.ErrorCodes = append(ecs, orasrv.ErrorCodes...)
+			}
+			if *flagServeDefaultErrorCodes {
+				orasrv.ErrorCodes = append(orasrv.ErrorCodes, orasrv.DefaultErrorCodes...)
+			}
+			if err := os.MkdirAll(*flagServePkgCacheDir, 0775); err != nil {
+				return fmt.Errorf("mkdirAll %s: %w", *flagServePkgCacheDir, err)
+			}
+			refresh := func(ctx context.Context) error {
+				return refreshPackageCaches(ctx, db, pattern, *flagServePkgCacheDir)
+			}
+			if err := refresh(ctx); err != nil {
+				return err
+			}
+			srv, err := dynamic.New(ctx, db, *flagServePkgCacheDir, filter)
+			if err != nil {
+				return err
+			}
+			srv.DbmsOutput = *flagServeDbmsOutput
+			checkAuth := func(context.Context, string) error { return nil }
+			if *flagServePolicy != "" {
+				fh, err := os.Open(*flagServePolicy)
+				if err != nil {
+					return err
+				}
+				policy, err := orasrv.ReadPolicy(fh)
+				fh.Close()
+				if err != nil {
+					return fmt.Errorf("%s: %w", *flagServePolicy, err)
+				}
+				authz := orasrv.Authorizer{Policy: policy, MetadataKey: *flagServeRolesMetadata,
+					Resolver: srv.Resolver(), Tags: srv.MethodTags, Audit: logger.WithGroup("audit")}
+				checkAuth = authz.CheckAuth
+			}
+			metrics := orasrv.NewMetrics(db)
+			metrics.Resolver = srv.Resolver()
+			options := append(metrics.ServerOptions(), srv.ServerOption(), orasrv.WithResolver(srv.Resolver()),
+				orasrv.WithHealth(db, 0), orasrv.WithDrain(*flagServeDrain))
+			if *flagServeUserMetadata != "" || *flagServeClientIDMetadata != "" {
+				if *flagServeUserMetadata != "" {
+					// the proxied sessions must not be reused for other users
+					db.SetMaxIdleConns(0)
+				}
+				options = append(options, orasrv.WithIdentity(
+					orasrv.MetadataIdentity(*flagServeUserMetadata, *flagServeClientIDMetadata)))
+			}
+			var authenticators []auth.Authenticator
+			if *flagServeJWKS != "" {
+				keys, err := auth.ReadJWKS(*flagServeJWKS)
+				if err != nil {
+					return err
+				}
+				authenticators = append(authenticators, &auth.JWT{Keys: keys,
+					Issuer: *flagServeJWTIssuer, Audience: *flagServeJWTAudience})
+			}
+			if *flagServeBasicLogin {
+				P, err := godror.ParseConnString(dsn)
+				if err != nil {
+					return err
+				}
+				authenticators = append(authenticators, &auth.Basic{Verify: auth.OracleLogin(P)})
+			}
+			if len(authenticators) != 0 {
+				options = append(options, orasrv.WithAuthenticator(auth.Chain(authenticators...)))
+			}
+			gs := orasrv.GRPCServer(ctx, logger, verbose > 1, checkAuth, options...)
+			srv.Register(gs)
+			lis, err := net.Listen("tcp", *flagServeListen)
+			if err != nil {
+				return err
+			}
+			grp, grpCtx := errgroup.WithContext(ctx)
+			if *flagServeReload > 0 {
+				grp.Go(func() error {
+					if err := srv.Watch(grpCtx, *flagServeReload, refresh); !errors.Is(err, context.Canceled) {
+						return err
+					}
+					return nil
+				})
+			}
+			grp.Go(func() error {
+				<-grpCtx.Done()
+				gs.GracefulStop()
+				return nil
+			})
+			if *flagServeMetrics != "" {
+				mux := http.NewServeMux()
+				mux.Handle("/metrics", metrics.Handler())
+				hs := &http.Server{Addr: *flagServeMetrics, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+				grp.Go(func() error {
+					if err := hs.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+						return err
+					}
+					return nil
+				})
+				grp.Go(func() error {
+					<-grpCtx.Done()
+					return hs.Shutdown(context.Background())
+				})
+			}
+			logger.Info("serving", "address", lis.Addr().String(), "services", slices.Sorted(maps.Keys(srv.GetServiceInfo())))
+			if err := gs.Serve(lis); err != nil {
+				return err
+			}
+			return grp.Wait()
+		},
+	}
+
 	FS = ff.NewFlagSet("oracall")
 	FS.Value('v', "verbose", &verbose, "verbose logging")
 	FS.StringVar(&dsn, 0, "connect", "", "connect to DB for retrieving function arguments")
 	app := ff.Command{Name: "oracall", Flags: FS,
-		Subcommands: []*ff.Command{&callCmd, &genModelCmd, &updateCmd},
+		Subcommands: []*ff.Command{&callCmd, &genModelCmd, &updateCmd, &diffCmd, &serveCmd},
 	}
 
 	if err := app.Parse(os.Args[1:]); err != nil {
@@ -380,14 +640,16 @@ func Main() error {
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
-	ctx, cancel = context.WithTimeout(ctx, 10*time.Minute)
-	defer cancel()
+	if app.GetSelected() != &serveCmd {
+		ctx, cancel = context.WithTimeout(ctx, 10*time.Minute)
+		defer cancel()
+	}
 
 	return app.Run(ctx)
 }
 
 type dbRow struct {
-	Package, Object, InOut sql.NullString
+	Package, Object, InOut, Overload, Defaulted sql.NullString
 	dbType
 	SubID    sql.NullInt64
 	OID, Seq int
@@ -471,7 +733,7 @@ func parseDB(
            package_name, object_name,
            data_level, argument_name, in_out,
            data_type, data_precision, data_scale, character_set_name, NULL AS index_by,
-           pls_type, char_length, type_owner, type_name, type_subname, type_link
+           pls_type, char_length, type_owner, type_name, type_subname, type_link, overload, defaulted
       FROM ` + tbl + `
       WHERE data_type <> 'OBJECT' AND package_name||'.'||object_name LIKE UPPER(:1)
      UNION ALL
@@ -480,7 +742,7 @@ func parseDB(
             A.data_level, B.attr_name, A.in_out,
             B.ATTR_TYPE_NAME, B.PRECISION, B.scale, B.character_set_name, NULL AS index_by,
             NVL2(B.ATTR_TYPE_OWNER, B.attr_type_owner||'.', '')||B.attr_type_name, B.length,
-			NULL, NULL, NULL, NULL
+			NULL, NULL, NULL, NULL, A.overload, 'N'
        FROM all_type_attrs B, ` + tbl + ` A
        WHERE B.owner = A.type_owner AND B.type_name = A.type_name AND
              A.data_type = 'OBJECT' AND
@@ -582,6 +844,7 @@ func parseDB(
 				&row.Level, &row.Argument, &row.InOut,
 				&row.Data, &row.Prec, &row.Scale, &row.Charset, &row.IndexBy,
 				&row.PLS, &row.Length, &row.Owner, &row.Name, &row.Subname, &row.Link,
+				&row.Overload, &row.Defaulted,
 			); err != nil {
 				return fmt.Errorf("reading row=%v: %w", rows, err)
 			}
@@ -655,6 +918,10 @@ func parseDB(
 	var docsMu sync.Mutex
 	var replMu sync.Mutex
 	docs := make(map[string]string)
+	// cursors holds the described columns of the weak cursors (--oracall:cursor), by PKG.FUNCTION and argument name.
+	cursors := make(map[string]map[string][]oracall.UserArgument)
+	// results holds the described implicit result sets (--oracall:result), by PKG.FUNCTION, in order.
+	results := make(map[string][]oracall.ResultCache)
 	// pkgUAs buffers UserArguments per package for cache writing (used when pkgCacheDir != "").
 	var pkgUAs map[string]oracall.PackageCache
 	if pkgCacheDir != "" {
@@ -736,6 +1003,28 @@ func parseDB(
 							// logger.Warn("annotation", "a", a)
 							a.Package = ua.PackageName
 							annotations = append(annotations, a)
+							if a.Type == "cursor" {
+								cols, err := describeCursor(ctx, tx1, a.Other)
+								if err != nil {
+									logger.Warn("describe cursor", "annotation", a, "error", err)
+									continue
+								}
+								fn, arg, _ := strings.Cut(strings.ToUpper(a.Name), ".")
+								fn = ua.PackageName + "." + fn
+								if cursors[fn] == nil {
+									cursors[fn] = make(map[string][]oracall.UserArgument)
+								}
+								cursors[fn][arg] = cols
+							} else if a.Type == "result" {
+								cols, err := describeCursor(ctx, tx1, a.Other)
+								if err != nil {
+									logger.Warn("describe result", "annotation", a, "error", err)
+									continue
+								}
+								fn, nm, _ := strings.Cut(strings.ToUpper(a.Name), ".")
+								fn = ua.PackageName + "." + fn
+								results[fn] = append(results[fn], oracall.ResultCache{Name: nm, Columns: cols})
+							}
 						}
 					}
 					if s := funDocs[""]; s != "" {
@@ -768,6 +1057,10 @@ func parseDB(
 			if row.Object.Valid {
 				ua.ObjectName = row.Object.String
 			}
+			if row.Overload.Valid {
+				ua.Overload = row.Overload.String
+			}
+			ua.Defaulted = row.Level == 0 && row.Defaulted.String == "Y"
 			if row.Argument != "" {
 				ua.ArgumentName = row.Argument
 			}
@@ -827,18 +1120,31 @@ func parseDB(
 					p.Functions = make(map[string]oracall.FunctionCache)
 					pkgUAs[ua.PackageName] = p
 				}
-				f, ok := p.Functions[ua.ObjectName]
+				key := ua.FunctionKey()
+				f, ok := p.Functions[key]
 				if !ok {
-					f.Name = ua.ObjectName
+					f.Name, f.Overload = ua.ObjectName, ua.Overload
 				}
 				f.Arguments = append(f.Arguments, ua)
-				p.Functions[ua.ObjectName] = f
+				p.Functions[key] = f
 			}
 		}
 		return nil
 	})
+	groupedArgs := make(chan []oracall.UserArgument, 16)
+	grp.Go(func() error { oracall.FilterAndGroup(groupedArgs, userArgs, filter); return nil })
 	filteredArgs := make(chan []oracall.UserArgument, 16)
-	grp.Go(func() error { oracall.FilterAndGroup(filteredArgs, userArgs, filter); return nil })
+	grp.Go(func() error {
+		defer close(filteredArgs)
+		for uas := range groupedArgs {
+			replMu.Lock()
+			cc := cursors[uas[0].PackageName+"."+uas[0].ObjectName]
+			rr := results[uas[0].PackageName+"."+uas[0].ObjectName]
+			replMu.Unlock()
+			filteredArgs <- oracall.AppendResults(oracall.ExpandCursors(uas, cc), rr)
+		}
+		return nil
+	})
 	functions = oracall.ParseArguments(filteredArgs, filter)
 	if grpErr := grp.Wait(); grpErr != nil {
 		logger.Error("ParseArguments", "error", grpErr)
@@ -853,7 +1159,10 @@ func parseDB(
 	var any bool
 	for i, f := range functions {
 		if f.Documentation == "" {
-			if f.Documentation = docs[f.Name()]; f.Documentation == "" {
+			if f.Documentation = docs[f.Name()]; f.Documentation == "" && f.Overload() != "" {
+				f.Documentation = docs[strings.TrimSuffix(f.Name(), "_"+f.Overload())]
+			}
+			if f.Documentation == "" {
 				any = true
 			} else {
 				functions[i] = f
@@ -878,11 +1187,14 @@ func parseDB(
 			for k, v := range docs {
 				k = strings.ToUpper(k)
 				if fn, ok := strings.CutPrefix(k, pn); ok {
-					f, ok := pc.Functions[fn]
-					if ok {
-						f.Documentation = v
-						pc.Functions[fn] = f
-					} else {
+					var found bool
+					for key, f := range pc.Functions { // all the overloads
+						if f.Name == fn {
+							f.Documentation, found = v, true
+							pc.Functions[key] = f
+						}
+					}
+					if !found {
 						logger.Warn("no function", "fn", fn)
 					}
 				} else {
@@ -894,6 +1206,16 @@ func parseDB(
 					pc.Annotations = append(pc.Annotations, a)
 				}
 			}
+			for key, f := range pc.Functions {
+				if cc := cursors[pkgName+"."+f.Name]; len(cc) != 0 {
+					f.Cursors = cc
+					pc.Functions[key] = f
+				}
+				if rr := results[pkgName+"."+f.Name]; len(rr) != 0 {
+					f.Results = rr
+					pc.Functions[key] = f
+				}
+			}
 			if err = oracall.WritePackageCache(ctx, pkgCacheDir, pc); err != nil {
 				return packages, functions, annotations, fmt.Errorf("WritePackageCache %s: %w", pkgName, err)
 			}
@@ -903,8 +1225,93 @@ func parseDB(
 	return packages, functions, annotations, nil
 }
 
+// parseSources reads the package specifications from the given files,
+// and writes the per-package cache files into pkgCacheDir, if not empty.
+// The LastDDL of the packages is the modification time of the file.
+func parseSources(
+	ctx context.Context, fileNames []string, owner, pkgCacheDir string, filter func(string) bool,
+) (
+	packages map[string]string, functions []oracall.Function, annotations []oracall.Annotation, err error,
+) {
+	for _, fn := range fileNames {
+		if fn = strings.TrimSpace(fn); fn == "" {
+			continue
+		}
+		fi, err := os.Stat(fn)
+		if err != nil {
+			return packages, functions, annotations, err
+		}
+		b, err := os.ReadFile(fn)
+		if err != nil {
+			return packages, functions, annotations, err
+		}
+		pcs, err := source.ParseSpec(ctx, string(b), owner, fi.ModTime())
+		if err != nil {
+			return packages, functions, annotations, fmt.Errorf("%s: %w", fn, err)
+		}
+		for _, pc := range pcs {
+			if filter != nil && !filter(pc.Name+".") {
+				logger.Debug("SKIP", "pkgName", pc.Name)
+				continue
+			}
+			if pc.Documentation != "" {
+				if packages == nil {
+					packages = make(map[string]string)
+				}
+				packages[pc.Name] = pc.Documentation
+			}
+			annotations = append(annotations, pc.Annotations...)
+			functions = append(functions, oracall.ParsePackageCache(ctx, pc, filter)...)
+			if pkgCacheDir == "" {
+				continue
+			}
+			if err = os.MkdirAll(pkgCacheDir, 0775); err != nil {
+				return packages, functions, annotations, fmt.Errorf("mkdirAll %s: %w", pkgCacheDir, err)
+			}
+			if err = oracall.WritePackageCache(ctx, pkgCacheDir, pc); err != nil {
+				return packages, functions, annotations, fmt.Errorf("WritePackageCache %s: %w", pc.Name, err)
+			}
+		}
+	}
+	return packages, functions, annotations, nil
+}
+
 var bufPool = sync.Pool{New: func() any { return bytes.NewBuffer(make([]byte, 0, 1024)) }}
 
+// refreshPackageCaches rereads the packages matching pattern whose LAST_DDL_TIME
+// differs from the one in their cache in dir.
+func refreshPackageCaches(ctx context.Context, db *sql.DB, pattern, dir string) error {
+	pat, _, _ := strings.Cut(pattern, ".")
+	const qry = `SELECT object_name, last_ddl_time FROM user_objects WHERE object_type = 'PACKAGE' AND object_name LIKE UPPER(:1)`
+	rows, err := db.QueryContext(ctx, qry, pat)
+	if err != nil {
+		return fmt.Errorf("%s: %w", qry, err)
+	}
+	defer rows.Close()
+	var changed []string
+	for rows.Next() {
+		var nm string
+		var t time.Time
+		if err := rows.Scan(&nm, &t); err != nil {
+			return fmt.Errorf("scan %s: %w", qry, err)
+		}
+		if pc, err := oracall.ReadPackageCache(ctx, dir, nm); err == nil && pc.LastDDL.Equal(t) {
+			continue
+		}
+		changed = append(changed, nm)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	for _, nm := range changed {
+		logger.Info("refresh", "package", nm)
+		if _, _, _, err := parseDB(ctx, db, nm, "", dir, nil); err != nil {
+			return fmt.Errorf("%s: %w", nm, err)
+		}
+	}
+	return nil
+}
+
 func getSource(ctx context.Context, w io.Writer, tx *sql.Tx, packageName string) error {
 	qry := "SELECT text FROM user_source WHERE name = UPPER(:1) AND type = 'PACKAGE' ORDER BY line"
 	rows, err := tx.QueryContext(ctx, qry, packageName, godror.PrefetchCount(129))
@@ -928,6 +1335,45 @@ func getSource(ctx context.Context, w io.Writer, tx *sql.Tx, packageName string)
 	return nil
 }
 
+// describeCursor returns the columns of the (shape) query, as the fields of a weak cursor's record.
+func describeCursor(ctx context.Context, tx queryExecer, qry string) ([]oracall.UserArgument, error) {
+	qry = "SELECT * FROM (" + strings.TrimSuffix(strings.TrimSpace(qry), ";") + ") WHERE 1=0"
+	rows, err := tx.QueryContext(ctx, qry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", qry, err)
+	}
+	defer rows.Close()
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", qry, err)
+	}
+	cols := make([]oracall.UserArgument, 0, len(cts))
+	for _, ct := range cts {
+		ua := oracall.UserArgument{ArgumentName: ct.Name(), DataType: ct.DatabaseTypeName()}
+		switch ua.DataType {
+		case "NVARCHAR2", "LONG":
+			ua.DataType = "VARCHAR2"
+		case "NCHAR":
+			ua.DataType = "CHAR"
+		case "FLOAT", "DOUBLE":
+			ua.DataType = "NUMBER"
+		default:
+			if strings.HasPrefix(ua.DataType, "TIMESTAMP") {
+				ua.DataType = "TIMESTAMP"
+			}
+		}
+		ua.PlsType = ua.DataType
+		if prec, scale, ok := ct.DecimalSize(); ok {
+			ua.DataPrecision, ua.DataScale = uint8(prec), uint8(scale)
+		}
+		if length, ok := ct.Length(); ok {
+			ua.CharLength = uint(length)
+		}
+		cols = append(cols, ua)
+	}
+	return cols, rows.Close()
+}
+
 type queryExecer interface {
 	godror.Querier
 	godror.Execer
@@ -0,0 +1,249 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+	"github.com/tgulacsi/oracall/auth"
+	oracall "github.com/tgulacsi/oracall/lib"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Policy maps the tags of the methods ((oracall.orasrv.tag) options) to the roles allowed to call them.
+//
+//	{"tags": {"admin": ["dba"], "report": ["analyst", "dba"]}, "default": ["*"]}
+type Policy struct {
+	// Tags maps a tag to the allowed roles; "*" allows everybody.
+	// The caller must have an allowed role for each tag of the method listed here.
+	Tags map[string][]string `json:"tags"`
+	// Default are the roles allowed to call the methods without any tag listed in Tags.
+	// Empty allows everybody.
+	Default []string `json:"default,omitempty"`
+	// DryRun overrides the policy for the dry runs (see oracall.IsDryRun), if not nil:
+	// {"dry_run": {"tags": {"calc": ["viewer"]}}} lets viewers preview the calc methods.
+	// The tags it does not list - and its Default, if empty - are checked by this policy.
+	DryRun *Policy `json:"dry_run,omitempty"`
+}
+
+// ReadPolicy reads the JSON policy file.
+func ReadPolicy(r io.Reader) (Policy, error) {
+	var p Policy
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&p)
+	return p, err
+}
+
+// Allowed reports whether the roles allow calling the method with the tags.
+func (p Policy) Allowed(tags, roles []string) bool {
+	allowed := func(want []string) bool {
+		return slices.Contains(want, "*") || slices.ContainsFunc(roles, func(r string) bool { return slices.Contains(want, r) })
+	}
+	var checked bool
+	for _, t := range tags {
+		want, ok := p.Tags[t]
+		if !ok {
+			continue
+		}
+		if !allowed(want) {
+			return false
+		}
+		checked = true
+	}
+	return checked || len(p.Default) == 0 || allowed(p.Default)
+}
+
+// dryRun returns the policy of the dry runs: DryRun over p.
+func (p Policy) dryRun() Policy {
+	if p.DryRun == nil {
+		return p
+	}
+	dr := Policy{Tags: maps.Clone(p.Tags), Default: p.Default}
+	if dr.Tags == nil {
+		dr.Tags = make(map[string][]string, len(p.DryRun.Tags))
+	}
+	maps.Copy(dr.Tags, p.DryRun.Tags)
+	if len(p.DryRun.Default) != 0 {
+		dr.Default = p.DryRun.Default
+	}
+	return dr
+}
+
+// Authorizer checks the calls against a Policy, with the tags of the called method.
+//
+// Its CheckAuth is usable as the checkAuth of GRPCServer.
+type Authorizer struct {
+	Policy Policy
+	// MetadataKey is the key of the caller's roles (comma separated) in the incoming metadata.
+	// Empty disables it - set it only behind a proxy which sets (and strips) it!
+	MetadataKey string
+	// Resolver finds the descriptors of the methods, with their (oracall.orasrv.tag) options,
+	// such as the Resolver of the dynamic server; protoregistry.GlobalFiles if nil.
+	Resolver Resolver
+	// Tags returns the tags of the method (/pkg.Service/Method) not found in the descriptors,
+	// such as the Tags of the generated server, or the MethodTags of the dynamic one.
+	Tags func(fullMethod string) []string
+	// Audit logs the denials; the logger of the context is used if nil.
+	Audit *slog.Logger
+}
+
+// CheckAuth returns a PermissionDenied error if the caller's roles do not allow calling the method
+// (dry running it, if the Policy has a DryRun one).
+func (a *Authorizer) CheckAuth(ctx context.Context, fullMethod string) error {
+	tags := tagsOf(a.Resolver, fullMethod)
+	if len(tags) == 0 && a.Tags != nil {
+		tags = a.Tags(fullMethod)
+	}
+	roles := CallerRoles(ctx, a.MetadataKey)
+	policy, dryRun := a.Policy, oracall.IsDryRun(ctx)
+	if dryRun {
+		policy = policy.dryRun()
+	}
+	if policy.Allowed(tags, roles) {
+		return nil
+	}
+	logger := a.Audit
+	if logger == nil {
+		if logger = FromContext(ctx); logger == nil {
+			logger = slog.Default()
+		}
+	}
+	var addr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr = p.Addr.String()
+	}
+	var principal string
+	if p, ok := auth.FromContext(ctx); ok {
+		principal = p.Name
+	}
+	logger.Warn("permission denied", "audit", true, "method", fullMethod, "tags", tags, "roles", roles, "principal", principal, "peer", addr, "dryRun", dryRun)
+	return status.Errorf(codes.PermissionDenied, "%s: permission denied", fullMethod)
+}
+
+// authenticatorOption authenticates the callers before checkAuth.
+type authenticatorOption struct {
+	grpc.EmptyServerOption
+	authenticator auth.Authenticator
+}
+
+// WithAuthenticator is a GRPCServer option authenticating the callers with a (such as an auth.Chain)
+// before checkAuth, putting the principal into the context of the call (see auth.FromContext).
+// The calls without (or with wrong) credentials fail as Unauthenticated.
+func WithAuthenticator(a auth.Authenticator) grpc.ServerOption {
+	return authenticatorOption{authenticator: a}
+}
+
+// authenticate returns the context with the principal of a, if not nil.
+func authenticate(ctx context.Context, a auth.Authenticator) (context.Context, error) {
+	if a == nil {
+		return ctx, nil
+	}
+	p, err := a.Authenticate(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	return auth.NewContext(ctx, p), nil
+}
+
+// CallerRoles returns the roles of the caller: the roles of its authenticated principal,
+// the organizational units of its verified TLS certificate,
+// and the comma separated roles of the metadataKey in the incoming metadata, if not empty.
+func CallerRoles(ctx context.Context, metadataKey string) []string {
+	var roles []string
+	if p, ok := auth.FromContext(ctx); ok {
+		roles = append(roles, p.Roles...)
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if ti, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(ti.State.VerifiedChains) != 0 && len(ti.State.VerifiedChains[0]) != 0 {
+			roles = append(roles, ti.State.VerifiedChains[0][0].Subject.OrganizationalUnit...)
+		}
+	}
+	if metadataKey != "" {
+		for _, v := range metadata.ValueFromIncomingContext(ctx, metadataKey) {
+			for r := range strings.SplitSeq(v, ",") {
+				if r = strings.TrimSpace(r); r != "" {
+					roles = append(roles, r)
+				}
+			}
+		}
+	}
+	return roles
+}
+
+var methodTags sync.Map // fullMethod -> []string
+
+// tagsOf returns the (oracall.orasrv.tag) options of the method ("/pkg.Service/Method"), found with r.
+//
+// Only the methods of the global registry (nil r) are cached, as the others may change.
+func tagsOf(r Resolver, fullMethod string) []string {
+	if r == nil {
+		if v, ok := methodTags.Load(fullMethod); ok {
+			return v.([]string)
+		}
+	}
+	var tags []string
+	if md := findMethod(r, fullMethod); md != nil && md.Options() != nil {
+		tags = stringsOption(md.Options(), E_Tag)
+	}
+	if r == nil {
+		methodTags.Store(fullMethod, tags)
+	}
+	return tags
+}
+
+// stringsOption returns the values of the repeated string option xt in opts,
+// by its name, as the options of the dynamic server have another (dynamic) type of it.
+func stringsOption(opts proto.Message, xt protoreflect.ExtensionType) []string {
+	name := xt.TypeDescriptor().FullName()
+	var ss []string
+	opts.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.FullName() != name || !fd.IsList() {
+			return true
+		}
+		l := v.List()
+		for i := range l.Len() {
+			ss = append(ss, l.Get(i).String())
+		}
+		return false
+	})
+	return ss
+}
+
+// Resolver finds the descriptors by their full name, as protoregistry.Files does.
+type Resolver interface {
+	FindDescriptorByName(protoreflect.FullName) (protoreflect.Descriptor, error)
+}
+
+// findMethod returns the descriptor of the method ("/pkg.Service/Method") found with r
+// (protoregistry.GlobalFiles if nil), or nil.
+func findMethod(r Resolver, fullMethod string) protoreflect.MethodDescriptor {
+	if r == nil {
+		r = protoregistry.GlobalFiles
+	}
+	name := protoreflect.FullName(strings.ReplaceAll(strings.TrimPrefix(fullMethod, "/"), "/", "."))
+	if d, err := r.FindDescriptorByName(name); err == nil {
+		if md, ok := d.(protoreflect.MethodDescriptor); ok {
+			return md
+		}
+	}
+	return nil
+}
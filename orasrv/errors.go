@@ -0,0 +1,231 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	oracall "github.com/tgulacsi/oracall/lib"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ErrorCode maps the ORA error codes From..To to a gRPC code.
+type ErrorCode struct {
+	From, To int
+	Code     codes.Code
+}
+
+// ErrorCodes maps the ORA errors to gRPC codes, after the (oracall.orasrv.error) options of the method.
+// The first matching entry wins; the unmapped ORA errors are Unknown.
+//
+// It is empty by default: set it to DefaultErrorCodes (or read it with ReadErrorCodes) to map the common ones.
+var ErrorCodes []ErrorCode
+
+// DefaultErrorCodes is a suggested ErrorCodes table.
+var DefaultErrorCodes = []ErrorCode{
+	{From: 1, To: 1, Code: codes.AlreadyExists},              // DUP_VAL_ON_INDEX
+	{From: 54, To: 54, Code: codes.Unavailable},              // resource busy
+	{From: 60, To: 60, Code: codes.Aborted},                  // deadlock
+	{From: 1013, To: 1013, Code: codes.Canceled},             // user requested cancel
+	{From: 1031, To: 1031, Code: codes.PermissionDenied},     // insufficient privileges
+	{From: 1403, To: 1403, Code: codes.NotFound},             // NO_DATA_FOUND
+	{From: 1722, To: 1722, Code: codes.InvalidArgument},      // INVALID_NUMBER
+	{From: 3113, To: 3114, Code: codes.Unavailable},          // end-of-file on communication channel
+	{From: 6502, To: 6502, Code: codes.InvalidArgument},      // VALUE_ERROR
+	{From: 12170, To: 12170, Code: codes.Unavailable},        // connect timeout
+	{From: 12541, To: 12541, Code: codes.Unavailable},        // no listener
+	{From: 20000, To: 20999, Code: codes.FailedPrecondition}, // RAISE_APPLICATION_ERROR
+}
+
+// ParseErrorCode parses the "1403=NOT_FOUND", "20001..20099=INVALID_ARGUMENT" or "NO_DATA_FOUND=NOT_FOUND" mapping.
+//
+// Negative (-20001) and ORA-01403 forms are accepted, too.
+func ParseErrorCode(s string) (ErrorCode, error) {
+	var ec ErrorCode
+	ora, code, ok := strings.Cut(s, "=")
+	if !ok {
+		return ec, fmt.Errorf("%q: no =", s)
+	}
+	if err := ec.Code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(strings.TrimSpace(code))))); err != nil {
+		return ec, fmt.Errorf("%q: %w", s, err)
+	}
+	from, to, isRange := strings.Cut(strings.TrimSpace(ora), "..")
+	var err error
+	if ec.From, err = parseORACode(from); err != nil {
+		return ec, fmt.Errorf("%q: %w", s, err)
+	}
+	ec.To = ec.From
+	if isRange {
+		if ec.To, err = parseORACode(to); err != nil {
+			return ec, fmt.Errorf("%q: %w", s, err)
+		}
+	}
+	if ec.From > ec.To {
+		ec.From, ec.To = ec.To, ec.From
+	}
+	return ec, nil
+}
+
+func parseORACode(s string) (int, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if code, ok := oracall.ExceptionCodes[s]; ok {
+		return code, nil
+	}
+	code, err := strconv.Atoi(strings.TrimPrefix(s, "ORA-"))
+	if code < 0 {
+		code = -code
+	}
+	return code, err
+}
+
+// ReadErrorCodes reads the error code table: a ParseErrorCode mapping per line, # starts a comment.
+func ReadErrorCodes(r io.Reader) ([]ErrorCode, error) {
+	var ecs []ErrorCode
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		ec, err := ParseErrorCode(line)
+		if err != nil {
+			return ecs, err
+		}
+		ecs = append(ecs, ec)
+	}
+	return ecs, scanner.Err()
+}
+
+// MethodErrorCodes returns the error codes of the (oracall.orasrv.error) options of the method.
+// Invalid entries are skipped.
+func MethodErrorCodes(md protoreflect.MethodDescriptor) []ErrorCode {
+	if md == nil || md.Options() == nil {
+		return nil
+	}
+	var ecs []ErrorCode
+	for _, s := range stringsOption(md.Options(), E_Error) {
+		if ec, err := ParseErrorCode(s); err == nil {
+			ecs = append(ecs, ec)
+		}
+	}
+	return ecs
+}
+
+// resolverOption finds the descriptors of the methods with resolver.
+type resolverOption struct {
+	grpc.EmptyServerOption
+	resolver Resolver
+}
+
+// WithResolver is a GRPCServer option finding the descriptors of the methods,
+// for their (oracall.orasrv.error) options, with r instead of protoregistry.GlobalFiles,
+// such as the Resolver of the dynamic server.
+func WithResolver(r Resolver) grpc.ServerOption { return resolverOption{resolver: r} }
+
+var methodErrorCodes sync.Map // fullMethod -> []ErrorCode
+
+// errorCodesOf returns the error codes of the method ("/pkg.Service/Method"), found with r.
+//
+// Only the methods of the global registry (nil r) are cached, as the others may change.
+func errorCodesOf(r Resolver, fullMethod string) []ErrorCode {
+	if r != nil {
+		return MethodErrorCodes(findMethod(r, fullMethod))
+	}
+	if v, ok := methodErrorCodes.Load(fullMethod); ok {
+		return v.([]ErrorCode)
+	}
+	ecs := MethodErrorCodes(findMethod(nil, fullMethod))
+	methodErrorCodes.Store(fullMethod, ecs)
+	return ecs
+}
+
+// oraCodeOf returns the (positive) ORA error code of err, or 0.
+func oraCodeOf(err error) int {
+	var oc interface{ Code() int }
+	if err == nil || !errors.As(err, &oc) || oc == nil {
+		return 0
+	}
+	if code := oc.Code(); code < 0 {
+		return -code
+	}
+	return oc.Code()
+}
+
+// StatusError converts the error to a gRPC status error, mapping the ORA error codes with ErrorCodes.
+func StatusError(err error) error { return StatusErrorWith(err, nil) }
+
+// StatusErrorWith is StatusError, trying the given error codes first.
+//
+// The status carries an ErrorInfo detail with the ORA error code, and the failing line of the statement,
+// and a BadRequest detail for InvalidArgument.
+func StatusErrorWith(err error, ecs []ErrorCode) error {
+	if err == nil {
+		return nil
+	}
+	var gs interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &gs) && gs != nil {
+		return err
+	}
+	var code codes.Code
+	var sc interface {
+		Code() codes.Code
+	}
+	oraCode := oraCodeOf(err)
+	if errors.Is(err, oracall.ErrInvalidArgument) {
+		code = codes.InvalidArgument
+	} else if errors.Is(err, oracall.ErrTxNotFound) {
+		code = codes.NotFound
+	} else if errors.As(err, &sc) && sc != nil {
+		code = sc.Code()
+	} else if oraCode != 0 {
+	Loop:
+		for _, table := range [][]ErrorCode{ecs, ErrorCodes} {
+			for _, ec := range table {
+				if ec.From <= oraCode && oraCode <= ec.To {
+					code = ec.Code
+					break Loop
+				}
+			}
+		}
+	}
+	if code == 0 {
+		return err
+	}
+	st := status.New(code, err.Error())
+	if oraCode == 0 {
+		return st.Err()
+	}
+	info := errdetails.ErrorInfo{
+		Reason: fmt.Sprintf("ORA-%05d", oraCode), Domain: "oracle",
+		Metadata: map[string]string{"code": strconv.Itoa(oraCode)},
+	}
+	var qe *oracall.QueryError
+	if errors.As(err, &qe) && qe != nil && qe.LineNo() != 0 {
+		info.Metadata["line"] = strconv.Itoa(qe.LineNo())
+		info.Metadata["statement"] = strings.TrimSpace(qe.Line())
+	}
+	details := []protoadapt.MessageV1{&info}
+	if code == codes.InvalidArgument {
+		details = append(details, &errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Description: err.Error(), Reason: info.Reason},
+		}})
+	}
+	if withDetails, dErr := st.WithDetails(details...); dErr == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
@@ -0,0 +1,187 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+	"github.com/godror/godror"
+	"github.com/tgulacsi/oracall/auth"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// healthOption registers the health service, checking db periodically.
+type healthOption struct {
+	grpc.EmptyServerOption
+	db       *sql.DB
+	interval time.Duration
+}
+
+// reflectionOption registers the server reflection service.
+type reflectionOption struct{ grpc.EmptyServerOption }
+
+// drainOption drains the server at the end of the global context.
+type drainOption struct {
+	grpc.EmptyServerOption
+	timeout time.Duration
+}
+
+// WithHealth is a GRPCServer option registering the gRPC health service,
+// which is SERVING while db answers the ping (checked every interval, 10s if zero),
+// and its godror session pool is not exhausted.
+func WithHealth(db *sql.DB, interval time.Duration) grpc.ServerOption {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return healthOption{db: db, interval: interval}
+}
+
+// WithReflection is a GRPCServer option registering the gRPC server reflection service.
+func WithReflection() grpc.ServerOption { return reflectionOption{} }
+
+// WithDrain is a GRPCServer option draining the server when its global context ends:
+// the health turns NOT_SERVING, no new RPCs are accepted, and the running ones
+// (such as the cursor streams) are waited for at most timeout - then they are cancelled.
+func WithDrain(timeout time.Duration) grpc.ServerOption { return drainOption{timeout: timeout} }
+
+// serverOptions are the options of GRPCServer which are not grpc.ServerOptions.
+type serverOptions struct {
+	health        *healthOption
+	reflection    bool
+	drain         *drainOption
+	identify      IdentifyFunc
+	authenticator auth.Authenticator
+	resolver      Resolver
+}
+
+// splitOptions separates the options of GRPCServer from the grpc.ServerOptions.
+func splitOptions(options []grpc.ServerOption) ([]grpc.ServerOption, serverOptions) {
+	var so serverOptions
+	grpcOpts := options[:0:0]
+	for _, o := range options {
+		switch o := o.(type) {
+		case healthOption:
+			so.health = &o
+		case reflectionOption:
+			so.reflection = true
+		case drainOption:
+			so.drain = &o
+		case identityOption:
+			so.identify = o.identify
+		case authenticatorOption:
+			so.authenticator = o.authenticator
+		case resolverOption:
+			so.resolver = o.resolver
+		default:
+			grpcOpts = append(grpcOpts, o)
+		}
+	}
+	return grpcOpts, so
+}
+
+// register registers the health and reflection services on gs, and starts the health checking and draining goroutines.
+func register(globalCtx context.Context, logger *slog.Logger, gs *grpc.Server, so serverOptions) {
+	var hs *health.Server
+	if ho := so.health; ho != nil {
+		hs = health.NewServer()
+		healthpb.RegisterHealthServer(gs, hs)
+		go watchHealth(globalCtx, logger, hs, ho.db, ho.interval)
+	}
+	if so.reflection {
+		reflection.Register(gs)
+	}
+	if do := so.drain; do != nil {
+		go func() {
+			<-globalCtx.Done()
+			if hs != nil {
+				hs.Shutdown()
+			}
+			drain(gs, do.timeout)
+		}()
+	}
+}
+
+// drain stops gs gracefully, waiting for the running RPCs at most timeout.
+func drain(gs *grpc.Server, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() { gs.GracefulStop(); close(done) }()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		gs.Stop()
+	}
+}
+
+// watchHealth sets the serving status of hs after checkDB, every interval, till ctx ends.
+func watchHealth(ctx context.Context, logger *slog.Logger, hs *health.Server, db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		st := healthpb.HealthCheckResponse_SERVING
+		err := checkDB(ctx, db, interval)
+		if err != nil {
+			st = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		if st != last {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Info("health", "status", st.String(), "error", err)
+			hs.SetServingStatus("", st)
+			last = st
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkDB pings db, and checks that its session pool is not exhausted.
+func checkDB(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		return err
+	}
+	if stats, ok := poolStats(ctx, db); ok && stats.Max != 0 && stats.Busy >= stats.Max {
+		return fmt.Errorf("session pool exhausted: %s", stats)
+	}
+	return nil
+}
+
+// poolStats returns the session pool statistics of godror, if db is a godror DB.
+func poolStats(ctx context.Context, db *sql.DB) (godror.PoolStats, bool) {
+	var stats godror.PoolStats
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return stats, false
+	}
+	defer conn.Close()
+	var ok bool
+	err = conn.Raw(func(dc any) error {
+		c, isGodror := dc.(godror.Conn)
+		if !isGodror {
+			return nil
+		}
+		var err error
+		stats, err = c.GetPoolStats()
+		ok = err == nil
+		return err
+	})
+	return stats, ok && err == nil
+}
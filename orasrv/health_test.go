@@ -0,0 +1,93 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// pingConnector connects to a fake DB, which answers the ping unless down.
+type pingConnector struct{ down *atomic.Bool }
+
+func (pc pingConnector) Connect(context.Context) (driver.Conn, error) { return pingConn(pc), nil }
+func (pc pingConnector) Driver() driver.Driver                        { return nil }
+
+type pingConn struct{ down *atomic.Bool }
+
+func (c pingConn) Prepare(string) (driver.Stmt, error) { return nil, errors.ErrUnsupported }
+func (c pingConn) Close() error                        { return nil }
+func (c pingConn) Begin() (driver.Tx, error)           { return nil, errors.ErrUnsupported }
+func (c pingConn) Ping(context.Context) error {
+	if c.down.Load() {
+		return errors.New("database is down")
+	}
+	return nil
+}
+
+func TestHealth(t *testing.T) {
+	var down atomic.Bool
+	db := sql.OpenDB(pingConnector{down: &down})
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gs := GRPCServer(ctx, NewT(t), false, func(context.Context, string) error { return nil },
+		WithHealth(db, 10*time.Millisecond), WithReflection(), WithDrain(time.Second))
+	if _, ok := gs.GetServiceInfo()["grpc.reflection.v1.ServerReflection"]; !ok {
+		t.Errorf("no reflection: %v", gs.GetServiceInfo())
+	}
+	lis := bufconn.Listen(1 << 20)
+	served := make(chan error, 1)
+	go func() { served <- gs.Serve(lis) }()
+
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	hc := healthpb.NewHealthClient(cc)
+	waitFor := func(want healthpb.HealthCheckResponse_ServingStatus) {
+		t.Helper()
+		var got healthpb.HealthCheckResponse_ServingStatus
+		for range 100 {
+			if resp, err := hc.Check(ctx, &healthpb.HealthCheckRequest{}); err == nil {
+				if got = resp.GetStatus(); got == want {
+					return
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("got %s, wanted %s", got, want)
+	}
+	waitFor(healthpb.HealthCheckResponse_SERVING)
+	down.Store(true)
+	waitFor(healthpb.HealthCheckResponse_NOT_SERVING)
+	down.Store(false)
+	waitFor(healthpb.HealthCheckResponse_SERVING)
+
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("not drained")
+	}
+}
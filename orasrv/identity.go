@@ -0,0 +1,81 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+
+	"github.com/tgulacsi/oracall/auth"
+	oracall "github.com/tgulacsi/oracall/lib"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// IdentifyFunc returns the identity of the caller of the method.
+type IdentifyFunc func(ctx context.Context, fullMethod string) (oracall.Identity, error)
+
+// identityOption puts the identity of the caller into the context of the calls.
+type identityOption struct {
+	grpc.EmptyServerOption
+	identify IdentifyFunc
+}
+
+// WithIdentity is a GRPCServer option putting the caller's identity into the context
+// of the authorized calls (with oracall.ContextWithIdentity), so the calls run
+// as that user (proxy authentication), with its CLIENT_IDENTIFIER - see oracall.BeginTx.
+//
+// identify is called after checkAuth: it may return what checkAuth has authenticated,
+// such as MetadataIdentity. Its error fails the call as Unauthenticated.
+func WithIdentity(identify IdentifyFunc) grpc.ServerOption {
+	return identityOption{identify: identify}
+}
+
+// MetadataIdentity returns an IdentifyFunc reading the proxy user from the userKey,
+// and the client identifier from the clientIDKey of the incoming metadata (any of them may be empty).
+//
+// Use it only behind a proxy which sets (and strips) these!
+func MetadataIdentity(userKey, clientIDKey string) IdentifyFunc {
+	first := func(md metadata.MD, key string) string {
+		if key != "" {
+			if vv := md.Get(key); len(vv) != 0 {
+				return vv[0]
+			}
+		}
+		return ""
+	}
+	return func(ctx context.Context, _ string) (oracall.Identity, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		return oracall.Identity{User: first(md, userKey), ClientID: first(md, clientIDKey)}, nil
+	}
+}
+
+// withIdentity returns the context with the identity of identify, if not nil.
+func withIdentity(ctx context.Context, identify IdentifyFunc, fullMethod string) (context.Context, error) {
+	if identify == nil {
+		return ctx, nil
+	}
+	id, err := identify(ctx, fullMethod)
+	if err != nil {
+		return ctx, err
+	}
+	return oracall.ContextWithIdentity(ctx, id), nil
+}
+
+// PrincipalIdentity returns an IdentifyFunc using the name of the authenticated principal (see WithAuthenticator)
+// as the client identifier - and as the proxy user, too, if proxy is true.
+func PrincipalIdentity(proxy bool) IdentifyFunc {
+	return func(ctx context.Context, _ string) (oracall.Identity, error) {
+		p, ok := auth.FromContext(ctx)
+		if !ok {
+			return oracall.Identity{}, nil
+		}
+		id := oracall.Identity{ClientID: p.Name}
+		if proxy {
+			id.User = p.Name
+		}
+		return id, nil
+	}
+}
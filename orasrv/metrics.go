@@ -0,0 +1,158 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package orasrv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Metrics are the Prometheus metrics of a gRPC server:
+// requests, latency, ORA errors and in-flight calls per method, rows streamed, and the DB pool statistics.
+//
+// Pass its ServerOptions to GRPCServer, and serve its Handler on /metrics.
+type Metrics struct {
+	// Resolver finds the descriptors of the methods, for their (oracall.orasrv.error) options,
+	// such as the Resolver of the dynamic server; protoregistry.GlobalFiles if nil.
+	Resolver Resolver
+
+	registry  *prometheus.Registry
+	requests  *prometheus.CounterVec
+	latency   *prometheus.HistogramVec
+	oraErrors *prometheus.CounterVec
+	inFlight  *prometheus.GaugeVec
+	rows      *prometheus.CounterVec
+}
+
+// NewMetrics returns new Metrics, with the sql.DBStats and godror pool statistics of db, if not nil.
+func NewMetrics(db *sql.DB) *Metrics {
+	m := Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "oracall", Name: "requests_total", Help: "Handled requests, by gRPC status code.",
+		}, []string{"method", "code"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "oracall", Name: "request_duration_seconds", Help: "Duration of the requests.",
+			Buckets: prometheus.ExponentialBuckets(0.005, 4, 9),
+		}, []string{"method"}),
+		oraErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "oracall", Name: "ora_errors_total", Help: "Failed requests, by ORA error code.",
+		}, []string{"method", "ora_code"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "oracall", Name: "requests_in_flight", Help: "Requests being handled.",
+		}, []string{"method"}),
+		rows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "oracall", Name: "streamed_rows_total", Help: "Rows (repeated field elements) sent in streams.",
+		}, []string{"method"}),
+	}
+	m.registry.MustRegister(m.requests, m.latency, m.oraErrors, m.inFlight, m.rows)
+	if db != nil {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(db, "oracall"), poolCollector{db: db})
+	}
+	return &m
+}
+
+// Handler returns the http.Handler serving the metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Registry returns the registry of the metrics, to register more collectors.
+func (m *Metrics) Registry() *prometheus.Registry { return m.registry }
+
+// ServerOptions returns the interceptors collecting the metrics.
+func (m *Metrics) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			done := m.start(info.FullMethod)
+			res, err := handler(ctx, req)
+			done(err)
+			return res, err
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			done := m.start(info.FullMethod)
+			err := handler(srv, rowCountingStream{ServerStream: ss, rows: m.rows.WithLabelValues(info.FullMethod)})
+			done(err)
+			return err
+		}),
+	}
+}
+
+// start counts the request of the method as in-flight, and returns the function recording its result.
+func (m *Metrics) start(fullMethod string) func(error) {
+	inFlight := m.inFlight.WithLabelValues(fullMethod)
+	inFlight.Inc()
+	start := time.Now()
+	return func(err error) {
+		inFlight.Dec()
+		m.latency.WithLabelValues(fullMethod).Observe(time.Since(start).Seconds())
+		code := status.Code(StatusErrorWith(err, errorCodesOf(m.Resolver, fullMethod)))
+		m.requests.WithLabelValues(fullMethod, code.String()).Inc()
+		if oraCode := oraCodeOf(err); oraCode != 0 {
+			m.oraErrors.WithLabelValues(fullMethod, fmt.Sprintf("ORA-%05d", oraCode)).Inc()
+		}
+	}
+}
+
+// rowCountingStream counts the elements of the repeated fields of the sent messages.
+type rowCountingStream struct {
+	grpc.ServerStream
+	rows prometheus.Counter
+}
+
+func (ss rowCountingStream) SendMsg(msg any) error {
+	if err := ss.ServerStream.SendMsg(msg); err != nil {
+		return err
+	}
+	if pm, ok := msg.(proto.Message); ok {
+		var n int
+		pm.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+			if fd.IsList() {
+				n += v.List().Len()
+			}
+			return true
+		})
+		ss.rows.Add(float64(n))
+	}
+	return nil
+}
+
+var (
+	poolBusyDesc = prometheus.NewDesc("oracall_pool_busy_sessions", "Busy sessions of the godror pool.", nil, nil)
+	poolOpenDesc = prometheus.NewDesc("oracall_pool_open_sessions", "Open sessions of the godror pool.", nil, nil)
+	poolMaxDesc  = prometheus.NewDesc("oracall_pool_max_sessions", "Maximum sessions of the godror pool.", nil, nil)
+)
+
+// poolCollector collects the session pool statistics of godror.
+type poolCollector struct{ db *sql.DB }
+
+func (pc poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolBusyDesc
+	ch <- poolOpenDesc
+	ch <- poolMaxDesc
+}
+
+func (pc poolCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stats, ok := poolStats(ctx, pc.db)
+	if !ok {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(poolBusyDesc, prometheus.GaugeValue, float64(stats.Busy))
+	ch <- prometheus.MustNewConstMetric(poolOpenDesc, prometheus.GaugeValue, float64(stats.Open))
+	ch <- prometheus.MustNewConstMetric(poolMaxDesc, prometheus.GaugeValue, float64(stats.Max))
+}
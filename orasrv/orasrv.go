@@ -7,7 +7,6 @@ package orasrv
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -30,6 +29,7 @@ import (
 	"google.golang.org/grpc/codes"
 	_ "google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
 	godror "github.com/godror/godror"
 )
@@ -51,6 +51,7 @@ func WithContext(ctx context.Context, logger *slog.Logger) context.Context {
 func NewT(t *testing.T) *slog.Logger { return zlog.NewT(t).SLog() }
 
 func GRPCServer(globalCtx context.Context, logger *slog.Logger, verbose bool, checkAuth func(ctx context.Context, path string) error, options ...grpc.ServerOption) *grpc.Server {
+	options, so := splitOptions(options)
 	erroredMethods := make(map[string]struct{})
 	var erroredMethodsMu sync.RWMutex
 
@@ -65,6 +66,9 @@ func GRPCServer(globalCtx context.Context, logger *slog.Logger, verbose bool, ch
 		reqID := ContextGetReqID(ctx)
 		ctx = ContextWithReqID(ctx, reqID)
 		lgr := logger.With("reqID", reqID)
+		if oracall.IsDryRun(ctx) {
+			lgr = lgr.With("dryRun", true)
+		}
 		ctx = zlog.NewSContext(ctx, lgr)
 		verbose := verbose
 		var wasThere bool
@@ -113,11 +117,19 @@ func GRPCServer(globalCtx context.Context, logger *slog.Logger, verbose bool, ch
 				}
 				lgr, commit, ctx, cancel := getLogger(ss.Context(), info.FullMethod)
 				defer cancel()
+				ctx, span := startSpan(ctx, info.FullMethod)
+				defer func() { endSpan(span, err) }()
 
 				lgr = lgr.With("method", info.FullMethod)
 				lgr.Info("checkAuth")
+				if ctx, err = authenticate(ctx, so.authenticator); err != nil {
+					return authError(err)
+				}
 				if err = checkAuth(ctx, info.FullMethod); err != nil {
-					return status.Error(codes.Unauthenticated, err.Error())
+					return authError(err)
+				}
+				if ctx, err = withIdentity(ctx, so.identify, info.FullMethod); err != nil {
+					return authError(err)
 				}
 
 				wss := grpc_middleware.WrapServerStream(ss)
@@ -131,7 +143,7 @@ func GRPCServer(globalCtx context.Context, logger *slog.Logger, verbose bool, ch
 					lvl = slog.LevelError
 				}
 				lgr.Log(ctx, lvl, "handler", "dur", dur.String(), "error", err)
-				return StatusError(err)
+				return StatusErrorWith(err, errorCodesOf(so.resolver, info.FullMethod))
 			}),
 
 		grpc.UnaryInterceptor(
@@ -141,25 +153,33 @@ func GRPCServer(globalCtx context.Context, logger *slog.Logger, verbose bool, ch
 				}
 				logger, commit, ctx, cancel := getLogger(ctx, info.FullMethod)
 				defer cancel()
+				ctx, span := startSpan(ctx, info.FullMethod)
+				defer func() { endSpan(span, err) }()
 				logger = logger.With("method", info.FullMethod)
 
+				if ctx, err = authenticate(ctx, so.authenticator); err != nil {
+					return nil, authError(err)
+				}
 				if err = checkAuth(ctx, info.FullMethod); err != nil {
-					return nil, status.Error(codes.Unauthenticated, err.Error())
+					return nil, authError(err)
+				}
+				if ctx, err = withIdentity(ctx, so.identify, info.FullMethod); err != nil {
+					return nil, authError(err)
 				}
 
 				ht := &iohlp.HeadTailKeeper{Limit: 1024}
 				jenc := json.NewEncoder(ht)
-				if err = jenc.Encode(req); err != nil {
-					logger.Error("marshal", "req", req, "error", err)
+				if err = jenc.Encode(redact(req)); err != nil {
+					logger.Error("marshal", "req", redact(req), "error", err)
 				}
-				reqS := StripJSON(ht.String(), "p_jelszo")
+				reqS := ht.String()
 				if logger.Enabled(ctx, slog.LevelDebug) {
 					logger.Debug("marshaled", "request", reqS)
 				}
 
 				// Fill PArgsHidden
 				if r := reflect.ValueOf(req).Elem(); r.Kind() != reflect.Struct {
-					logger.Info("not struct", "req", fmt.Sprintf("%T %#v", req, req))
+					logger.Info("not struct", "req", fmt.Sprintf("%T %#v", req, redact(req)))
 				} else {
 					if f := r.FieldByName("PArgsHidden"); f.IsValid() {
 						f.Set(reflect.ValueOf(ht.String()))
@@ -172,8 +192,8 @@ func GRPCServer(globalCtx context.Context, logger *slog.Logger, verbose bool, ch
 				commit(err)
 
 				ht.Reset()
-				if jErr := jenc.Encode(res); jErr != nil {
-					fmt.Fprintf(ht, ": %+v", res)
+				if jErr := jenc.Encode(redact(res)); jErr != nil {
+					fmt.Fprintf(ht, ": %+v", redact(res))
 					logger.Error("marshal", "request", reqS, "response", ht.String(), "error", jErr)
 				}
 				lvl := slog.LevelInfo
@@ -184,31 +204,22 @@ func GRPCServer(globalCtx context.Context, logger *slog.Logger, verbose bool, ch
 				logger.Log(ctx, lvl, "handled", "response", ht.String(),
 					"dur", dur.String(), "error", err)
 
-				return res, StatusError(err)
+				return res, StatusErrorWith(err, errorCodesOf(so.resolver, info.FullMethod))
 			}),
 	}
 	// it should be implemented in checkAuth
 	// nosemgrep: go.grpc.security.grpc-server-insecure-connection.grpc-server-insecure-connection
-	return grpc.NewServer(append(opts, options...)...)
+	gs := grpc.NewServer(append(opts, options...)...)
+	register(globalCtx, logger, gs, so)
+	return gs
 }
 
-func StatusError(err error) error {
-	if err == nil {
-		return nil
-	}
-	var code codes.Code
-	var sc interface {
-		Code() codes.Code
-	}
-	if errors.Is(err, oracall.ErrInvalidArgument) {
-		code = codes.InvalidArgument
-	} else if errors.As(err, &sc) && sc != nil {
-		code = sc.Code()
-	}
-	if code == 0 {
+// authError returns the status error of checkAuth as is (PermissionDenied), others as Unauthenticated.
+func authError(err error) error {
+	if _, ok := status.FromError(err); ok {
 		return err
 	}
-	return status.New(code, err.Error()).Err()
+	return status.Error(codes.Unauthenticated, err.Error())
 }
 
 type reqIDCtxKey struct{}
@@ -235,6 +246,14 @@ func NewULID() string {
 	return ulid.MustNew(ulid.Now(), ulid.DefaultEntropy()).String()
 }
 
+// redact returns the proto.Message with its (oracall.orasrv.secret) fields redacted.
+func redact(v any) any {
+	if m, ok := v.(proto.Message); ok {
+		return oracall.Redact(m)
+	}
+	return v
+}
+
 // StripJSON strips the given json tag's value (replaces with stars).
 // It's a poor man's password leak plug.
 func StripJSON(s, k string) string {
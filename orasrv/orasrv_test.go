@@ -4,7 +4,31 @@
 
 package orasrv
 
-import "testing"
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+	oracall "github.com/tgulacsi/oracall/lib"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
 
 func TestStripJSON(t *testing.T) {
 	for in, want := range map[string]string{
@@ -16,3 +40,235 @@ func TestStripJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestParseErrorCode(t *testing.T) {
+	for s, want := range map[string]ErrorCode{
+		"1403=NOT_FOUND":                  {From: 1403, To: 1403, Code: codes.NotFound},
+		"20001..20099 = invalid_argument": {From: 20001, To: 20099, Code: codes.InvalidArgument},
+		"-20100=ABORTED":                  {From: 20100, To: 20100, Code: codes.Aborted},
+		"ORA-00054=UNAVAILABLE":           {From: 54, To: 54, Code: codes.Unavailable},
+		"dup_val_on_index=ALREADY_EXISTS": {From: 1, To: 1, Code: codes.AlreadyExists},
+	} {
+		if got, err := ParseErrorCode(s); err != nil {
+			t.Errorf("%q: %+v", s, err)
+		} else if got != want {
+			t.Errorf("%q: got %+v, wanted %+v", s, got, want)
+		}
+	}
+	for _, s := range []string{"1403", "1403=NO_SUCH_CODE", "x=NOT_FOUND"} {
+		if got, err := ParseErrorCode(s); err == nil {
+			t.Errorf("%q: got %+v, wanted error", s, got)
+		}
+	}
+}
+
+type oraError int
+
+func (e oraError) Error() string { return fmt.Sprintf("ORA-%05d: error\nORA-06512: at line 2", int(e)) }
+func (e oraError) Code() int     { return int(e) }
+
+func TestStatusError(t *testing.T) {
+	qry := "BEGIN\n  pkg.fn(:1);\nEND;"
+	ecs := []ErrorCode{{From: 20001, To: 20099, Code: codes.InvalidArgument}}
+	tcs := []struct {
+		err  error
+		want codes.Code
+	}{
+		{err: oracall.NewQueryError(qry, oraError(1403)), want: codes.NotFound},
+		{err: oracall.NewQueryError(qry, oraError(20001)), want: codes.InvalidArgument},
+		{err: oracall.NewQueryError(qry, oraError(20100)), want: codes.FailedPrecondition},
+		{err: oracall.NewQueryError(qry, oraError(942)), want: codes.Unknown},
+		{err: fmt.Errorf("wrapped: %w", status.Error(codes.Aborted, "x")), want: codes.Aborted},
+	}
+	for _, tc := range tcs[:4] {
+		if got := status.Code(StatusErrorWith(tc.err, nil)); got != codes.Unknown {
+			t.Errorf("%v: got %s without the default error codes", tc.err, got)
+		}
+	}
+	ErrorCodes = DefaultErrorCodes
+	defer func() { ErrorCodes = nil }()
+	for _, tc := range tcs {
+		st := status.Convert(StatusErrorWith(tc.err, ecs))
+		if st.Code() != tc.want {
+			t.Errorf("%v: got %s, wanted %s", tc.err, st.Code(), tc.want)
+		}
+		if st.Code() == codes.Unknown || st.Code() == codes.Aborted {
+			continue
+		}
+		var info *errdetails.ErrorInfo
+		var br *errdetails.BadRequest
+		for _, d := range st.Details() {
+			switch d := d.(type) {
+			case *errdetails.ErrorInfo:
+				info = d
+			case *errdetails.BadRequest:
+				br = d
+			}
+		}
+		if info == nil || info.Domain != "oracle" || info.Metadata["line"] != "2" {
+			t.Errorf("%v: got %+v", tc.err, info)
+		}
+		if (br != nil) != (st.Code() == codes.InvalidArgument) {
+			t.Errorf("%v: got BadRequest %+v", tc.err, br)
+		}
+	}
+}
+
+func TestServerSpan(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	shutdown := SetupTelemetry(exp, nil)
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"traceparent", "00-"+traceID+"-00f067aa0ba902b7-01"))
+	_, span := startSpan(ctx, "/pkg.Pkg/Fn")
+	endSpan(span, status.Error(codes.NotFound, "nope"))
+	defer shutdown(context.Background())
+	if err := otel.GetTracerProvider().(*sdktrace.TracerProvider).ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	spans := exp.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+	s := spans[0]
+	if s.Name != "pkg.Pkg/Fn" || s.SpanContext.TraceID().String() != traceID || s.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("got %+v", s)
+	}
+	for _, kv := range s.Attributes {
+		if kv.Key == "rpc.grpc.status_code" && kv.Value.AsInt64() != int64(codes.NotFound) {
+			t.Errorf("got status %v", kv.Value)
+		}
+	}
+}
+
+type sentStream struct {
+	grpc.ServerStream
+	sent int
+}
+
+func (ss *sentStream) SendMsg(any) error { ss.sent++; return nil }
+
+func TestMetrics(t *testing.T) {
+	ErrorCodes = DefaultErrorCodes
+	defer func() { ErrorCodes = nil }()
+	m := NewMetrics(nil)
+	m.start("/pkg.Pkg/Get")(nil)
+	m.start("/pkg.Pkg/Get")(oracall.NewQueryError("BEGIN NULL; END;", oraError(1403)))
+	m.start("/pkg.Pkg/Get") // in flight
+
+	ss := sentStream{}
+	rcs := rowCountingStream{ServerStream: &ss, rows: m.rows.WithLabelValues("/pkg.Pkg/List")}
+	for range 2 {
+		if err := rcs.SendMsg(&structpb.ListValue{Values: []*structpb.Value{
+			structpb.NewNumberValue(1), structpb.NewNumberValue(2), structpb.NewNumberValue(3),
+		}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ss.sent != 2 {
+		t.Errorf("sent %d", ss.sent)
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`oracall_requests_total{code="OK",method="/pkg.Pkg/Get"} 1`,
+		`oracall_requests_total{code="NotFound",method="/pkg.Pkg/Get"} 1`,
+		`oracall_ora_errors_total{method="/pkg.Pkg/Get",ora_code="ORA-01403"} 1`,
+		`oracall_requests_in_flight{method="/pkg.Pkg/Get"} 1`,
+		`oracall_request_duration_seconds_count{method="/pkg.Pkg/Get"} 2`,
+		`oracall_streamed_rows_total{method="/pkg.Pkg/List"} 6`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %s from\n%s", want, body)
+		}
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	p, err := ReadPolicy(strings.NewReader(`{"tags": {"admin": ["dba"], "report": ["analyst", "dba"], "public": ["*"]}, "default": ["user"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		tags, roles []string
+		want        bool
+	}{
+		{tags: []string{"admin"}, roles: []string{"dba"}, want: true},
+		{tags: []string{"admin"}, roles: []string{"analyst"}, want: false},
+		{tags: []string{"admin", "report"}, roles: []string{"analyst"}, want: false},
+		{tags: []string{"report", "other"}, roles: []string{"analyst"}, want: true},
+		{tags: []string{"public"}, want: true},
+		{tags: []string{"other"}, roles: []string{"user"}, want: true},
+		{roles: []string{"analyst"}, want: false},
+	} {
+		if got := p.Allowed(tc.tags, tc.roles); got != tc.want {
+			t.Errorf("%q %q: got %t, wanted %t", tc.tags, tc.roles, got, tc.want)
+		}
+	}
+	if _, err := ReadPolicy(strings.NewReader(`{"roles": {}}`)); err == nil {
+		t.Error("unknown field accepted")
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	var buf strings.Builder
+	a := Authorizer{
+		Policy:      Policy{Tags: map[string][]string{"admin": {"dba"}}},
+		MetadataKey: "x-oracall-roles",
+		Tags:        func(string) []string { return []string{"admin"} },
+		Audit:       slog.New(slog.NewTextHandler(&buf, nil)),
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-oracall-roles", "user, analyst"))
+	err := a.CheckAuth(ctx, "/pkg.Pkg/Drop")
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("got %+v", err)
+	}
+	if s := buf.String(); !strings.Contains(s, "permission denied") || !strings.Contains(s, "method=/pkg.Pkg/Drop") {
+		t.Errorf("audit log: %s", s)
+	}
+	if err := authError(err); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("authError: got %+v", err)
+	}
+
+	cert := x509.Certificate{Subject: pkix.Name{CommonName: "app", OrganizationalUnit: []string{"dba"}}}
+	ctx = peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{&cert}}},
+	}})
+	if err := a.CheckAuth(ctx, "/pkg.Pkg/Drop"); err != nil {
+		t.Errorf("cert role: %+v", err)
+	}
+
+	a.Policy.DryRun = &Policy{Tags: map[string][]string{"admin": {"analyst"}}}
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-oracall-roles", "analyst"))
+	if err := a.CheckAuth(oracall.ContextWithDryRun(ctx), "/pkg.Pkg/Drop"); err != nil {
+		t.Errorf("dry run: %+v", err)
+	}
+	if err := a.CheckAuth(ctx, "/pkg.Pkg/Drop"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("not a dry run: got %+v", err)
+	}
+	// the tags not listed in the dry run policy are checked by the main one
+	a.Policy.DryRun = &Policy{Tags: map[string][]string{"calc": {"analyst"}}}
+	if err := a.CheckAuth(oracall.ContextWithDryRun(ctx), "/pkg.Pkg/Drop"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("dry run of an unlisted tag: got %+v", err)
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	_, so := splitOptions([]grpc.ServerOption{WithIdentity(MetadataIdentity("x-oracall-user", "x-oracall-client"))})
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-oracall-user", "scott", "x-oracall-client", "scott@example.com"))
+	ctx, err := withIdentity(ctx, so.identify, "/pkg.Pkg/Fn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := oracall.IdentityFromContext(ctx); !ok || id != (oracall.Identity{User: "scott", ClientID: "scott@example.com"}) {
+		t.Errorf("got %+v, %t", id, ok)
+	}
+
+	ctx, _ = withIdentity(context.Background(), so.identify, "/pkg.Pkg/Fn")
+	if id, ok := oracall.IdentityFromContext(ctx); ok {
+		t.Errorf("no metadata: got %+v", id)
+	}
+}
@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.36.5
+// 	protoc-gen-go v1.36.11
 // 	protoc        (unknown)
 // source: tag.proto
 
@@ -30,39 +30,91 @@ var file_tag_proto_extTypes = []protoimpl.ExtensionInfo{
 		Tag:           "bytes,13020,rep,name=tag",
 		Filename:      "tag.proto",
 	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         13021,
+		Name:          "oracall.orasrv.timeout",
+		Tag:           "bytes,13021,opt,name=timeout",
+		Filename:      "tag.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: ([]string)(nil),
+		Field:         13022,
+		Name:          "oracall.orasrv.error",
+		Tag:           "bytes,13022,rep,name=error",
+		Filename:      "tag.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         13024,
+		Name:          "oracall.orasrv.tx",
+		Tag:           "bytes,13024,opt,name=tx",
+		Filename:      "tag.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         13023,
+		Name:          "oracall.orasrv.secret",
+		Tag:           "varint,13023,opt,name=secret",
+		Filename:      "tag.proto",
+	},
 }
 
 // Extension fields to descriptorpb.MethodOptions.
 var (
 	// repeated string tag = 13020;
 	E_Tag = &file_tag_proto_extTypes[0]
+	// timeout of the call on the server side, as a Go duration (30s).
+	//
+	// optional string timeout = 13021;
+	E_Timeout = &file_tag_proto_extTypes[1]
+	// maps ORA error codes to gRPC codes: "1403=NOT_FOUND", "20001..20099=INVALID_ARGUMENT".
+	//
+	// repeated string error = 13022;
+	E_Error = &file_tag_proto_extTypes[2]
+	// transaction mode: comma separated readonly, serializable, nocommit.
+	//
+	// optional string tx = 13024;
+	E_Tx = &file_tag_proto_extTypes[3]
+)
+
+// Extension fields to descriptorpb.FieldOptions.
+var (
+	// the value of the field is redacted in the logs (--oracall:secret).
+	//
+	// optional bool secret = 13023;
+	E_Secret = &file_tag_proto_extTypes[4]
 )
 
 var File_tag_proto protoreflect.FileDescriptor
 
-var file_tag_proto_rawDesc = string([]byte{
-	0x0a, 0x09, 0x74, 0x61, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0e, 0x6f, 0x72, 0x61,
-	0x63, 0x61, 0x6c, 0x6c, 0x2e, 0x6f, 0x72, 0x61, 0x73, 0x72, 0x76, 0x1a, 0x20, 0x67, 0x6f, 0x6f,
-	0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x65, 0x73,
-	0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x3a, 0x31, 0x0a,
-	0x03, 0x74, 0x61, 0x67, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f, 0x70, 0x74,
-	0x69, 0x6f, 0x6e, 0x73, 0x18, 0xdc, 0x65, 0x20, 0x03, 0x28, 0x09, 0x52, 0x03, 0x74, 0x61, 0x67,
-	0x42, 0x2b, 0x5a, 0x29, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x74,
-	0x67, 0x75, 0x6c, 0x61, 0x63, 0x73, 0x69, 0x2f, 0x6f, 0x72, 0x61, 0x63, 0x61, 0x6c, 0x6c, 0x2f,
-	0x6f, 0x72, 0x61, 0x73, 0x72, 0x76, 0x3b, 0x6f, 0x72, 0x61, 0x73, 0x72, 0x76, 0x62, 0x06, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x33,
-})
+const file_tag_proto_rawDesc = "" +
+	"\n" +
+	"\ttag.proto\x12\x0eoracall.orasrv\x1a google/protobuf/descriptor.proto:1\n" +
+	"\x03tag\x12\x1e.google.protobuf.MethodOptions\x18\xdce \x03(\tR\x03tag:9\n" +
+	"\atimeout\x12\x1e.google.protobuf.MethodOptions\x18\xdde \x01(\tR\atimeout:5\n" +
+	"\x05error\x12\x1e.google.protobuf.MethodOptions\x18\xdee \x03(\tR\x05error:/\n" +
+	"\x02tx\x12\x1e.google.protobuf.MethodOptions\x18\xe0e \x01(\tR\x02tx:6\n" +
+	"\x06secret\x12\x1d.google.protobuf.FieldOptions\x18\xdfe \x01(\bR\x06secretB+Z)github.com/tgulacsi/oracall/orasrv;orasrvb\x06proto3"
 
 var file_tag_proto_goTypes = []any{
 	(*descriptorpb.MethodOptions)(nil), // 0: google.protobuf.MethodOptions
+	(*descriptorpb.FieldOptions)(nil),  // 1: google.protobuf.FieldOptions
 }
 var file_tag_proto_depIdxs = []int32{
 	0, // 0: oracall.orasrv.tag:extendee -> google.protobuf.MethodOptions
-	1, // [1:1] is the sub-list for method output_type
-	1, // [1:1] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	0, // [0:1] is the sub-list for extension extendee
+	0, // 1: oracall.orasrv.timeout:extendee -> google.protobuf.MethodOptions
+	0, // 2: oracall.orasrv.error:extendee -> google.protobuf.MethodOptions
+	0, // 3: oracall.orasrv.tx:extendee -> google.protobuf.MethodOptions
+	1, // 4: oracall.orasrv.secret:extendee -> google.protobuf.FieldOptions
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	0, // [0:5] is the sub-list for extension extendee
 	0, // [0:0] is the sub-list for field type_name
 }
 
@@ -78,7 +130,7 @@ func file_tag_proto_init() {
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_tag_proto_rawDesc), len(file_tag_proto_rawDesc)),
 			NumEnums:      0,
 			NumMessages:   0,
-			NumExtensions: 1,
+			NumExtensions: 5,
 			NumServices:   0,
 		},
 		GoTypes:           file_tag_proto_goTypes,
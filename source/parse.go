@@ -8,9 +8,11 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/UNO-SOFT/zlog/v2"
 	oracall "github.com/tgulacsi/oracall/lib"
@@ -125,6 +127,20 @@ func parseAnnotation(b string) (oracall.Annotation, error) {
 			a.Name = strings.TrimSpace(b)
 		} else {
 			a.Name = strings.TrimSpace(b[:i])
+			if a.Type == "timeout" {
+				a.Other = strings.TrimSpace(b[i+1:])
+				if _, err := time.ParseDuration(a.Other); err != nil {
+					return a, fmt.Errorf("%s: %w", b, err)
+				}
+				return a, nil
+			}
+			if a.Type == "tx" {
+				a.Other = strings.TrimSpace(b[i+1:])
+				if _, err := oracall.ParseTxMode(a.Other); err != nil {
+					return a, fmt.Errorf("%s: %w", b, err)
+				}
+				return a, nil
+			}
 			size, err := strconv.Atoi(strings.TrimSpace(b[i+1:]))
 			if err != nil {
 				return a, err
@@ -0,0 +1,751 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	oracall "github.com/tgulacsi/oracall/lib"
+)
+
+// ErrUnknownType is returned for types that cannot be resolved from the source alone,
+// such as %TYPE, %ROWTYPE or schema-level object types.
+var ErrUnknownType = errors.New("unknown type")
+
+// ParseSpec parses the package specifications (CREATE [OR REPLACE] PACKAGE ... END;) in src,
+// and returns the arguments of their procedures and functions the same way
+// as they're read from user_arguments. Package bodies are skipped.
+//
+// owner is the schema of the package types, if the package name is not qualified.
+// Functions using types that cannot be resolved from the source are skipped with a warning.
+func ParseSpec(ctx context.Context, src, owner string, lastDDL time.Time) ([]oracall.PackageCache, error) {
+	logger := zlog.SFromContext(ctx)
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := specParser{src: src, toks: toks, types: make(map[string]*plsType)}
+	var pcs []oracall.PackageCache
+	pkgDocs := make(map[string]map[string]string)
+	for !p.eof() {
+		if !p.is("PACKAGE") {
+			p.i++
+			continue
+		}
+		start := p.tok().pos
+		if p.i++; p.accept("BODY") {
+			continue
+		}
+		pc, err := p.parsePackage(ctx, owner, lastDDL)
+		if err != nil {
+			return pcs, err
+		}
+		docs, annotations, err := Parse(ctx, src[start:p.toks[p.i-1].end])
+		if err != nil {
+			return pcs, fmt.Errorf("%s: %w", pc.Name, err)
+		}
+		upDocs := make(map[string]string, len(docs))
+		for k, v := range docs {
+			upDocs[strings.ToUpper(k)] = v
+		}
+		pkgDocs[pc.Name] = upDocs
+		pc.Documentation = upDocs[""]
+		for k, f := range pc.Functions {
+			f.Documentation = upDocs[f.Name]
+			pc.Functions[k] = f
+		}
+		for _, a := range annotations {
+			a.Package = pc.Name
+			pc.Annotations = append(pc.Annotations, a)
+		}
+		pcs = append(pcs, pc)
+	}
+
+	// replace_docs can refer to any package parsed here.
+	for i, pc := range pcs {
+		annotations := pc.Annotations[:0]
+		for _, a := range pc.Annotations {
+			if a.Type != "replace_docs" {
+				annotations = append(annotations, a)
+				continue
+			}
+			other, nm, _ := strings.Cut(a.Other, ".")
+			if nm == "" {
+				nm = a.Name
+			}
+			f, ok := pc.Functions[strings.ToUpper(a.Name)]
+			if s := pkgDocs[strings.ToUpper(other)][strings.ToUpper(nm)]; ok && s != "" {
+				f.Documentation = s
+				pc.Functions[strings.ToUpper(a.Name)] = f
+			} else {
+				logger.Warn("cannot replace_docs", "annotation", a)
+			}
+		}
+		pcs[i].Annotations = annotations
+	}
+	return pcs, nil
+}
+
+type plsType struct {
+	err     error
+	elem    *plsType
+	subtype string
+	fields  []plsField
+	ua      oracall.UserArgument
+}
+
+type plsField struct {
+	typ  *plsType
+	name string
+}
+
+type token struct {
+	val      string // upper-cased, if not quoted
+	pos, end int
+	quoted   bool // "quoted identifier" or 'string literal'
+}
+
+type specParser struct {
+	types      map[string]*plsType // PKG.TYPE
+	src        string
+	pkg, owner string
+	toks       []token
+	i          int
+}
+
+func (p *specParser) parsePackage(ctx context.Context, owner string, lastDDL time.Time) (oracall.PackageCache, error) {
+	logger := zlog.SFromContext(ctx)
+	name := p.dotted()
+	if len(name) == 0 || len(name) > 2 {
+		return oracall.PackageCache{}, p.errorf("package name expected")
+	}
+	if len(name) == 2 {
+		owner = name[0]
+	}
+	p.pkg, p.owner = name[len(name)-1], owner
+	for !p.is("AS") && !p.is("IS") { // AUTHID, ACCESSIBLE BY...
+		if p.eof() {
+			return oracall.PackageCache{}, p.errorf("%s: AS expected", p.pkg)
+		}
+		p.i++
+	}
+	p.i++
+
+	pc := oracall.PackageCache{Name: p.pkg, LastDDL: lastDDL, Functions: make(map[string]oracall.FunctionCache)}
+	base := oracall.UserArgument{PackageName: p.pkg, LastDDL: lastDDL}
+	// subprograms in declaration order, nil arguments for the skipped ones,
+	// as the overloads are numbered by their order.
+	type subprogram struct {
+		name string
+		uas  []oracall.UserArgument
+	}
+	var subs []subprogram
+	count := make(map[string]int)
+	for {
+		switch {
+		case p.eof():
+			return pc, p.errorf("%s: END expected", p.pkg)
+
+		case p.accept("END"):
+			p.skipPast(";")
+			seen := make(map[string]int, len(count))
+			for _, sub := range subs {
+				var overload string
+				if count[sub.name] > 1 {
+					seen[sub.name]++
+					overload = strconv.Itoa(seen[sub.name])
+				}
+				if len(sub.uas) == 0 { // skipped
+					continue
+				}
+				for i := range sub.uas {
+					sub.uas[i].Overload = overload
+				}
+				key := sub.uas[0].FunctionKey()
+				f := pc.Functions[key]
+				f.Name, f.Overload = sub.name, overload
+				f.Arguments = append(f.Arguments, sub.uas...)
+				pc.Functions[key] = f
+			}
+			return pc, nil
+
+		case p.accept("TYPE"):
+			if err := p.parseTypeDecl(); err != nil {
+				return pc, err
+			}
+
+		case p.accept("SUBTYPE"):
+			nm, ok := p.ident()
+			if !ok || !p.accept("IS") {
+				return pc, p.errorf("SUBTYPE name IS expected")
+			}
+			t, err := p.parseType()
+			if err != nil && !errors.Is(err, ErrUnknownType) {
+				return pc, err
+			}
+			st := &plsType{err: err}
+			if t != nil {
+				*st = *t
+				if t.fields == nil && t.elem == nil {
+					st.subtype = nm
+				}
+			}
+			p.types[p.pkg+"."+nm] = st
+			p.skipPast(";")
+
+		case p.is("PROCEDURE") || p.is("FUNCTION"):
+			base.SubprogramID++
+			nm, uas, err := p.parseSubprogram(base)
+			if err != nil {
+				if !errors.Is(err, ErrUnknownType) {
+					return pc, err
+				}
+				logger.Warn("skip", "function", p.pkg+"."+nm, "error", err)
+				uas = nil
+			}
+			subs = append(subs, subprogram{name: nm, uas: uas})
+			count[nm]++
+
+		default: // variables, constants, exceptions, cursors, pragmas
+			p.skipPast(";")
+		}
+	}
+}
+
+func (p *specParser) parseTypeDecl() error {
+	nm, ok := p.ident()
+	if !ok || !p.accept("IS") {
+		return p.errorf("TYPE name IS expected")
+	}
+	t := &plsType{ua: oracall.UserArgument{
+		TypeOwner: p.owner, TypeName: p.pkg, TypeSubname: nm,
+		PlsType: strings.TrimPrefix(p.owner+"."+p.pkg+"."+nm, "."),
+	}}
+	elemType := func() error {
+		elem, err := p.parseType()
+		if err != nil {
+			if !errors.Is(err, ErrUnknownType) {
+				return err
+			}
+			t.err = err
+		}
+		t.elem = elem
+		return nil
+	}
+	switch {
+	case p.accept("RECORD"):
+		t.ua.DataType = "PL/SQL RECORD"
+		if !p.accept("(") {
+			return p.errorf("%s: ( expected", nm)
+		}
+		for {
+			fn, ok := p.ident()
+			if !ok {
+				return p.errorf("%s: field name expected", nm)
+			}
+			ft, err := p.parseType()
+			if err != nil {
+				if !errors.Is(err, ErrUnknownType) {
+					return err
+				}
+				t.err = err
+			}
+			p.skipDefault()
+			t.fields = append(t.fields, plsField{name: fn, typ: ft})
+			if !p.accept(",") {
+				break
+			}
+		}
+		if !p.accept(")") {
+			return p.errorf("%s: ) expected", nm)
+		}
+
+	case p.accept("TABLE"):
+		if !p.accept("OF") {
+			return p.errorf("%s: OF expected", nm)
+		}
+		if err := elemType(); err != nil {
+			return err
+		}
+		p.accept("NOT")
+		p.accept("NULL")
+		t.ua.DataType = "TABLE"
+		if p.accept("INDEX") && p.accept("BY") && !p.eof() {
+			t.ua.DataType = "PL/SQL TABLE"
+			t.ua.IndexBy = p.tok().val
+			if _, err := p.parseType(); err != nil {
+				return err
+			}
+		}
+
+	case p.accept("VARRAY") || p.accept("VARYING") && p.accept("ARRAY"):
+		p.skipSize()
+		if !p.accept("OF") {
+			return p.errorf("%s: OF expected", nm)
+		}
+		if err := elemType(); err != nil {
+			return err
+		}
+		t.ua.DataType = "TABLE"
+
+	case p.accept("REF"):
+		if !p.accept("CURSOR") {
+			return p.errorf("%s: CURSOR expected", nm)
+		}
+		// user_arguments has no type name for the cursor, only for its record
+		t.ua = oracall.UserArgument{DataType: "REF CURSOR", PlsType: "REF CURSOR"}
+		if p.accept("RETURN") {
+			if err := elemType(); err != nil {
+				return err
+			}
+		}
+
+	default:
+		t.err = fmt.Errorf("%s.%s: %w", p.pkg, nm, ErrUnknownType)
+	}
+	if t.err != nil && !errors.Is(t.err, ErrUnknownType) {
+		return t.err
+	}
+	p.types[p.pkg+"."+nm] = t
+	p.skipPast(";")
+	return nil
+}
+
+func (p *specParser) parseSubprogram(base oracall.UserArgument) (string, []oracall.UserArgument, error) {
+	isFunc := p.accept("FUNCTION")
+	if !isFunc {
+		p.accept("PROCEDURE")
+	}
+	nm, ok := p.ident()
+	if !ok {
+		return nm, nil, p.errorf("procedure name expected")
+	}
+	base.ObjectName = nm
+	type param struct {
+		typ         *plsType
+		name, inOut string
+		defaulted   bool
+	}
+	var params []param
+	var unknown error
+	if p.accept("(") {
+		for {
+			pn, ok := p.ident()
+			if !ok {
+				return nm, nil, p.errorf("%s: parameter name expected", nm)
+			}
+			inOut := "IN"
+			if p.accept("IN") {
+				if p.accept("OUT") {
+					inOut = "IN/OUT"
+				}
+			} else if p.accept("OUT") {
+				inOut = "OUT"
+			}
+			p.accept("NOCOPY")
+			t, err := p.parseType()
+			if err != nil {
+				if !errors.Is(err, ErrUnknownType) {
+					return nm, nil, err
+				}
+				if unknown == nil {
+					unknown = err
+				}
+			}
+			defaulted := p.skipDefault()
+			params = append(params, param{name: pn, inOut: inOut, typ: t, defaulted: defaulted})
+			if !p.accept(",") {
+				break
+			}
+		}
+		if !p.accept(")") {
+			return nm, nil, p.errorf("%s: ) expected", nm)
+		}
+	}
+	var ret *plsType
+	if isFunc {
+		if !p.accept("RETURN") {
+			return nm, nil, p.errorf("%s: RETURN expected", nm)
+		}
+		var err error
+		if ret, err = p.parseType(); err != nil {
+			if !errors.Is(err, ErrUnknownType) {
+				return nm, nil, err
+			}
+			if unknown == nil {
+				unknown = err
+			}
+		}
+	}
+	p.skipPast(";")
+	if unknown != nil {
+		return nm, nil, unknown
+	}
+
+	// numbered as in user_arguments: the return value is at position 0, the parameters from 1
+	var uas []oracall.UserArgument
+	if ret != nil {
+		base.InOut = "OUT"
+		uas = appendArgs(uas, base, "", ret, 0, 0)
+	} else if len(params) == 0 {
+		// a procedure without parameters has one row, without name and data type
+		base.InOut, base.Position = "IN", 1
+		uas = append(uas, base)
+	}
+	for i, prm := range params {
+		base.InOut = prm.inOut
+		n := len(uas)
+		uas = appendArgs(uas, base, prm.name, prm.typ, 0, uint(i+1))
+		uas[n].Defaulted = prm.defaulted
+	}
+	return nm, uas, nil
+}
+
+// appendArgs appends the rows of the argument, and the rows of its fields/elements one level deeper.
+//
+// The fields are numbered from 1 within their record, and the element of a collection is at position 1.
+func appendArgs(uas []oracall.UserArgument, base oracall.UserArgument, name string, t *plsType, level uint8, position uint) []oracall.UserArgument {
+	ua := t.ua
+	ua.PackageName, ua.ObjectName, ua.LastDDL = base.PackageName, base.ObjectName, base.LastDDL
+	ua.ObjectID, ua.SubprogramID, ua.InOut = base.ObjectID, base.SubprogramID, base.InOut
+	ua.ArgumentName, ua.DataLevel, ua.Position = name, level, position
+	if level == 0 && t.subtype != "" {
+		ua.PlsType = t.subtype
+	}
+	uas = append(uas, ua)
+	for i, f := range t.fields {
+		uas = appendArgs(uas, base, f.name, f.typ, level+1, uint(i+1))
+	}
+	if t.elem != nil {
+		uas = appendArgs(uas, base, "", t.elem, level+1, 1)
+	}
+	return uas
+}
+
+// parseType parses a type reference: a built-in type with its size,
+// or a type (subtype) declared previously.
+func (p *specParser) parseType() (*plsType, error) {
+	parts := p.dotted()
+	if len(parts) == 0 {
+		return nil, p.errorf("type expected")
+	}
+	name := strings.Join(parts, ".")
+	if p.accept("%") {
+		attr, _ := p.ident()
+		return nil, fmt.Errorf("%s%%%s: %w", name, attr, ErrUnknownType)
+	}
+	if len(parts) == 1 {
+		if t, ok, err := p.builtin(parts[0]); ok || err != nil {
+			return t, err
+		}
+	}
+	key := name
+	switch len(parts) {
+	case 1:
+		key = p.pkg + "." + name
+	case 3:
+		key = parts[1] + "." + parts[2]
+	}
+	if t := p.types[key]; t != nil {
+		return t, t.err
+	}
+	return nil, fmt.Errorf("%s: %w", name, ErrUnknownType)
+}
+
+func (p *specParser) builtin(name string) (*plsType, bool, error) {
+	var ua oracall.UserArgument
+	switch name {
+	case "VARCHAR2", "VARCHAR", "STRING":
+		ua.DataType, ua.CharacterSetName = "VARCHAR2", "CHAR_CS"
+	case "NVARCHAR2", "NCHAR", "NCLOB":
+		ua.DataType, ua.CharacterSetName = name, "NCHAR_CS"
+	case "CHAR", "CHARACTER":
+		ua.DataType, ua.CharacterSetName = "CHAR", "CHAR_CS"
+	case "CLOB":
+		ua.DataType, ua.CharacterSetName = name, "CHAR_CS"
+	case "LONG":
+		ua.DataType, ua.CharacterSetName = name, "CHAR_CS"
+		if p.accept("RAW") {
+			ua.DataType, ua.CharacterSetName = "LONG RAW", ""
+		}
+	case "NUMBER", "NUMERIC", "DECIMAL", "DEC":
+		ua.DataType = "NUMBER"
+	case "INTEGER", "INT", "SMALLINT":
+		ua.DataType, ua.DataPrecision = "NUMBER", 38
+	case "FLOAT", "REAL":
+		ua.DataType = "FLOAT"
+	case "DOUBLE":
+		if !p.accept("PRECISION") {
+			return nil, true, p.errorf("DOUBLE PRECISION expected")
+		}
+		ua.DataType = "FLOAT"
+	case "PLS_INTEGER", "BINARY_INTEGER", "SIMPLE_INTEGER",
+		"NATURAL", "NATURALN", "POSITIVE", "POSITIVEN", "SIGNTYPE":
+		ua.DataType = "BINARY_INTEGER"
+	case "BOOLEAN":
+		ua.DataType = "PL/SQL BOOLEAN"
+	case "SYS_REFCURSOR":
+		ua.DataType = "REF CURSOR"
+	case "RAW", "BINARY_FLOAT", "BINARY_DOUBLE", "DATE", "BLOB", "BFILE", "ROWID", "UROWID":
+		ua.DataType = name
+	case "TIMESTAMP":
+		ua.DataType = name
+		p.skipSize()
+		if p.accept("WITH") {
+			ua.DataType += " WITH TIME ZONE"
+			if p.accept("LOCAL") {
+				ua.DataType = "TIMESTAMP WITH LOCAL TIME ZONE"
+			}
+			if !p.accept("TIME") || !p.accept("ZONE") {
+				return nil, true, p.errorf("TIME ZONE expected")
+			}
+		}
+		ua.PlsType = ua.DataType
+		return &plsType{ua: ua}, true, nil
+	case "INTERVAL":
+		from, _ := p.ident()
+		p.skipSize()
+		if !p.accept("TO") {
+			return nil, true, p.errorf("INTERVAL %s TO expected", from)
+		}
+		to, _ := p.ident()
+		p.skipSize()
+		ua.DataType = "INTERVAL " + from + " TO " + to
+		ua.PlsType = ua.DataType
+		return &plsType{ua: ua}, true, nil
+	default:
+		return nil, false, nil
+	}
+	ua.PlsType = ua.DataType
+	if p.accept("(") {
+		a, err := p.number()
+		if err != nil {
+			return nil, true, err
+		}
+		var b int
+		if p.accept(",") {
+			if b, err = p.number(); err != nil {
+				return nil, true, err
+			}
+		}
+		if !p.accept("CHAR") {
+			p.accept("BYTE")
+		}
+		if !p.accept(")") {
+			return nil, true, p.errorf("%s: ) expected", name)
+		}
+		if ua.DataType == "NUMBER" || ua.DataType == "FLOAT" {
+			ua.DataPrecision, ua.DataScale = uint8(a), uint8(b)
+		} else {
+			ua.CharLength = uint(a)
+		}
+	}
+	return &plsType{ua: ua}, true, nil
+}
+
+func (p *specParser) number() (int, error) {
+	if p.accept("*") {
+		return 0, nil
+	}
+	if p.eof() {
+		return 0, p.errorf("number expected")
+	}
+	n, err := strconv.Atoi(p.tok().val)
+	if err != nil {
+		return 0, p.errorf("number expected: %w", err)
+	}
+	p.i++
+	return n, nil
+}
+
+// skipSize skips an optional (n) after the current token.
+func (p *specParser) skipSize() {
+	if p.accept("(") {
+		p.skipPast(")")
+	}
+}
+
+// skipDefault skips NOT NULL, := or DEFAULT expressions till the next , or ) on the same level,
+// and reports whether a default value was skipped.
+func (p *specParser) skipDefault() (defaulted bool) {
+	var depth int
+	for ; !p.eof(); p.i++ {
+		if t := p.tok(); !t.quoted {
+			switch t.val {
+			case "(":
+				depth++
+			case ")":
+				if depth == 0 {
+					return defaulted
+				}
+				depth--
+			case ",":
+				if depth == 0 {
+					return defaulted
+				}
+			case ";":
+				return defaulted
+			case ":=":
+				defaulted = true
+			default:
+				if depth == 0 && strings.EqualFold(t.val, "DEFAULT") {
+					defaulted = true
+				}
+			}
+		}
+	}
+	return defaulted
+}
+
+func (p *specParser) skipPast(s string) {
+	for ; !p.eof(); p.i++ {
+		if p.is(s) {
+			p.i++
+			return
+		}
+	}
+}
+
+// dotted returns the parts of a (possibly qualified) name.
+func (p *specParser) dotted() []string {
+	var parts []string
+	for {
+		nm, ok := p.ident()
+		if !ok {
+			return parts
+		}
+		parts = append(parts, nm)
+		if !p.accept(".") {
+			return parts
+		}
+	}
+}
+
+func (p *specParser) ident() (string, bool) {
+	if p.eof() {
+		return "", false
+	}
+	t := p.tok()
+	if t.quoted && t.val != "" && p.src[t.pos] == '"' || !t.quoted && isIdentStart(t.val[0]) {
+		p.i++
+		return t.val, true
+	}
+	return "", false
+}
+
+func (p *specParser) eof() bool  { return p.i >= len(p.toks) }
+func (p *specParser) tok() token { return p.toks[p.i] }
+func (p *specParser) is(s string) bool {
+	return !p.eof() && !p.toks[p.i].quoted && p.toks[p.i].val == s
+}
+func (p *specParser) accept(s string) bool {
+	if p.is(s) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *specParser) errorf(format string, args ...any) error {
+	pos := len(p.src)
+	if !p.eof() {
+		pos = p.tok().pos
+	}
+	line := strings.Count(p.src[:pos], "\n") + 1
+	return fmt.Errorf("line %d: "+format, append([]any{line}, args...)...)
+}
+
+func tokenize(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == '\v':
+			i++
+
+		case strings.HasPrefix(src[i:], lcBegin):
+			if j := strings.IndexByte(src[i:], '\n'); j < 0 {
+				i = len(src)
+			} else {
+				i += j + 1
+			}
+
+		case strings.HasPrefix(src[i:], bcBegin):
+			j := strings.Index(src[i+len(bcBegin):], bcEnd)
+			if j < 0 {
+				return toks, fmt.Errorf("unterminated comment at %d", i)
+			}
+			i += len(bcBegin) + j + len(bcEnd)
+
+		case c == '\'':
+			j := i + 1
+			for {
+				k := strings.IndexByte(src[j:], '\'')
+				if k < 0 {
+					return toks, fmt.Errorf("unterminated string at %d", i)
+				}
+				j += k + 1
+				if j < len(src) && src[j] == '\'' {
+					j++
+					continue
+				}
+				break
+			}
+			toks = append(toks, token{val: src[i:j], pos: i, end: j, quoted: true})
+			i = j
+
+		case c == '"':
+			k := strings.IndexByte(src[i+1:], '"')
+			if k < 0 {
+				return toks, fmt.Errorf("unterminated identifier at %d", i)
+			}
+			toks = append(toks, token{val: src[i+1 : i+1+k], pos: i, end: i + 2 + k, quoted: true})
+			i += k + 2
+
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(src) && isIdentChar(src[j]) {
+				j++
+			}
+			toks = append(toks, token{val: strings.ToUpper(src[i:j]), pos: i, end: j})
+			i = j
+
+		case '0' <= c && c <= '9':
+			j := i + 1
+			for j < len(src) && ('0' <= src[j] && src[j] <= '9' ||
+				src[j] == '.' && !strings.HasPrefix(src[j:], "..")) {
+				j++
+			}
+			toks = append(toks, token{val: src[i:j], pos: i, end: j})
+			i = j
+
+		default:
+			n := 1
+			if i+1 < len(src) {
+				switch src[i : i+2] {
+				case ":=", "=>", "..", "||", "<=", ">=", "<>", "!=":
+					n = 2
+				}
+			}
+			toks = append(toks, token{val: src[i : i+n], pos: i, end: i + n})
+			i += n
+		}
+	}
+	return toks, nil
+}
+
+// isIdentStart reports whether c can start an identifier ($ for conditional compilation).
+func isIdentStart(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '$' || c >= 0x80
+}
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || '0' <= c && c <= '9' || c == '_' || c == '#'
+}
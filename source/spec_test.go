@@ -0,0 +1,241 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package source_test
+
+import (
+	"context"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/google/go-cmp/cmp"
+	"github.com/tgulacsi/oracall/lib"
+	"github.com/tgulacsi/oracall/source"
+)
+
+func TestParseSpecDbWeb(t *testing.T) {
+	ctx := zlog.NewSContext(context.Background(), zlog.NewT(t).SLog())
+	b, err := os.ReadFile("../testdata/db_web.pck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pcs, err := source.ParseSpec(ctx, string(b), "BRUNO", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pcs) != 1 {
+		t.Fatalf("got %d packages, wanted 1", len(pcs))
+	}
+	pc := pcs[0]
+	if pc.Name != "DB_WEB" {
+		t.Errorf("got %q, wanted DB_WEB", pc.Name)
+	}
+
+	// compare with what the DB returned
+	fh, err := os.Open("../testdata/db_web.getriskvagyondetails.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	type row struct {
+		Name, InOut, DataType string
+		Level                 uint8
+		Position              uint
+	}
+	want := make(map[string][]row)
+	for ua, err := range oracall.NewUACsvReader(fh) {
+		if err != nil {
+			break
+		}
+		want[ua.ObjectName] = append(want[ua.ObjectName], row{Name: ua.ArgumentName, InOut: ua.InOut, DataType: ua.DataType, Level: ua.DataLevel, Position: ua.Position})
+	}
+	if len(pc.Functions) != len(want) {
+		t.Errorf("got %d functions, wanted %d", len(pc.Functions), len(want))
+	}
+	for nm, w := range want {
+		var got []row
+		for _, ua := range pc.Functions[nm].Arguments {
+			got = append(got, row{Name: ua.ArgumentName, InOut: ua.InOut, DataType: ua.DataType, Level: ua.DataLevel, Position: ua.Position})
+		}
+		if d := cmp.Diff(w, got); d != "" {
+			t.Errorf("%s: %s", nm, d)
+		}
+	}
+
+	functions := oracall.ParsePackageCache(ctx, pc, nil)
+	if len(functions) != 3 {
+		t.Errorf("got %d functions, wanted 3", len(functions))
+	}
+	for _, f := range functions {
+		if f.Name() != "DB_web.getriskvagyondetails" {
+			continue
+		}
+		arg := f.Args[2]
+		if arg.Type != "REF CURSOR" || arg.TableOf == nil || len(arg.TableOf.RecordOf) != 6 {
+			t.Errorf("%s: got %#v", arg.Name, arg)
+		}
+		if want := "BRUNO.DB_WEB.TELEPHELY_REC_TYP"; arg.TableOf.TypeName != want {
+			t.Errorf("got %q, wanted %q", arg.TableOf.TypeName, want)
+		}
+	}
+}
+
+func TestParseSpec(t *testing.T) {
+	ctx := zlog.NewSContext(context.Background(), zlog.NewT(t).SLog())
+	const src = `CREATE OR REPLACE EDITIONABLE PACKAGE "SCOTT".pkg AUTHID CURRENT_USER AS
+  -- # the package
+  --oracall:private secret
+  --oracall:timeout sum_it=30s
+  --oracall:tx recs=readonly
+  SUBTYPE id_t IS NUMBER(9);
+  c_x CONSTANT VARCHAR2(10) := 'a;b';
+  TYPE num_tab IS TABLE OF NUMBER INDEX BY PLS_INTEGER;
+  TYPE rec IS RECORD (a id_t NOT NULL := 1, b VARCHAR2(10 CHAR), c num_tab);
+  TYPE rec_nt IS TABLE OF rec;
+  ex EXCEPTION;
+  PRAGMA exception_init(ex, -20000);
+
+  /* summary */
+  FUNCTION sum_it(p_nums IN num_tab, p_opt IN OUT NOCOPY VARCHAR2 DEFAULT 'x,y') RETURN id_t;
+  PROCEDURE recs(p_recs OUT rec_nt, p_ts IN TIMESTAMP WITH TIME ZONE);
+  PROCEDURE secret;
+  PROCEDURE rowtype(p_emp IN emp%ROWTYPE);
+END pkg;
+/
+CREATE OR REPLACE PACKAGE BODY pkg AS
+  PROCEDURE secret IS BEGIN NULL; END;
+END;
+/
+`
+	pcs, err := source.ParseSpec(ctx, src, "", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pcs) != 1 {
+		t.Fatalf("got %d packages, wanted 1", len(pcs))
+	}
+	pc := pcs[0]
+	if pc.Name != "PKG" || !strings.Contains(pc.Documentation, "the package") {
+		t.Errorf("got %q %q", pc.Name, pc.Documentation)
+	}
+	if len(pc.Annotations) != 3 || pc.Annotations[0] != (oracall.Annotation{Package: "PKG", Type: "private", Name: "secret"}) ||
+		pc.Annotations[1] != (oracall.Annotation{Package: "PKG", Type: "timeout", Name: "sum_it", Other: "30s"}) ||
+		pc.Annotations[2] != (oracall.Annotation{Package: "PKG", Type: "tx", Name: "recs", Other: "readonly"}) {
+		t.Errorf("got annotations %v", pc.Annotations)
+	}
+	if _, ok := pc.Functions["ROWTYPE"]; ok {
+		t.Error("ROWTYPE should be skipped")
+	}
+	if _, ok := pc.Functions["SECRET"]; !ok {
+		t.Error("SECRET has no arguments, but should be kept")
+	}
+	if d := pc.Functions["SUM_IT"].Documentation; d != "summary" {
+		t.Errorf("got doc %q", d)
+	}
+
+	type row struct {
+		Name, InOut, DataType, PlsType, TypeName string
+		Level                                    uint8
+		Prec                                     uint8
+		Length                                   uint
+		Position                                 uint
+	}
+	got := func(nm string) []row {
+		var rows []row
+		for _, ua := range pc.Functions[nm].Arguments {
+			rows = append(rows, row{
+				Name: ua.ArgumentName, InOut: ua.InOut, DataType: ua.DataType, PlsType: ua.PlsType,
+				TypeName: ua.TypeOwner + "." + ua.TypeName + "." + ua.TypeSubname,
+				Level:    ua.DataLevel, Prec: ua.DataPrecision, Length: ua.CharLength, Position: ua.Position,
+			})
+		}
+		return rows
+	}
+	if d := cmp.Diff([]row{
+		{InOut: "OUT", DataType: "NUMBER", PlsType: "ID_T", TypeName: "..", Prec: 9},
+		{Name: "P_NUMS", InOut: "IN", DataType: "PL/SQL TABLE", PlsType: "SCOTT.PKG.NUM_TAB", TypeName: "SCOTT.PKG.NUM_TAB", Position: 1},
+		{InOut: "IN", DataType: "NUMBER", PlsType: "NUMBER", TypeName: "..", Level: 1, Position: 1},
+		{Name: "P_OPT", InOut: "IN/OUT", DataType: "VARCHAR2", PlsType: "VARCHAR2", TypeName: "..", Position: 2},
+	}, got("SUM_IT")); d != "" {
+		t.Error("SUM_IT:", d)
+	}
+	if d := cmp.Diff([]row{
+		{Name: "P_RECS", InOut: "OUT", DataType: "TABLE", PlsType: "SCOTT.PKG.REC_NT", TypeName: "SCOTT.PKG.REC_NT", Position: 1},
+		{InOut: "OUT", DataType: "PL/SQL RECORD", PlsType: "SCOTT.PKG.REC", TypeName: "SCOTT.PKG.REC", Level: 1, Position: 1},
+		{Name: "A", InOut: "OUT", DataType: "NUMBER", PlsType: "NUMBER", TypeName: "..", Level: 2, Prec: 9, Position: 1},
+		{Name: "B", InOut: "OUT", DataType: "VARCHAR2", PlsType: "VARCHAR2", TypeName: "..", Level: 2, Length: 10, Position: 2},
+		{Name: "C", InOut: "OUT", DataType: "PL/SQL TABLE", PlsType: "SCOTT.PKG.NUM_TAB", TypeName: "SCOTT.PKG.NUM_TAB", Level: 2, Position: 3},
+		{InOut: "OUT", DataType: "NUMBER", PlsType: "NUMBER", TypeName: "..", Level: 3, Position: 1},
+		{Name: "P_TS", InOut: "IN", DataType: "TIMESTAMP WITH TIME ZONE", PlsType: "TIMESTAMP WITH TIME ZONE", TypeName: "..", Position: 2},
+	}, got("RECS")); d != "" {
+		t.Error("RECS:", d)
+	}
+	// user_arguments has one row without name and data type for a procedure without parameters
+	if d := cmp.Diff([]row{{InOut: "IN", TypeName: "..", Position: 1}}, got("SECRET")); d != "" {
+		t.Error("SECRET:", d)
+	}
+	var found bool
+	for _, f := range oracall.ParsePackageCache(ctx, pc, nil) {
+		if found = f.RPCName() == "Secret"; found {
+			if len(f.Args) != 0 || f.Returns != nil {
+				t.Errorf("SECRET: got %v", f)
+			}
+			break
+		}
+	}
+	if !found {
+		t.Error("no SECRET function")
+	}
+}
+
+func TestParseSpecOverload(t *testing.T) {
+	ctx := zlog.NewSContext(context.Background(), zlog.NewT(t).SLog())
+	const src = `CREATE OR REPLACE PACKAGE scott.pkg AS
+  --oracall:rename get_data_2 => get_data_by_id
+  PROCEDURE get_data(p_name IN VARCHAR2, p_data OUT VARCHAR2);
+  PROCEDURE get_data(p_id IN NUMBER, p_data OUT VARCHAR2);
+  PROCEDURE single(p_id IN NUMBER);
+END pkg;
+`
+	pcs, err := source.ParseSpec(ctx, src, "", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc := pcs[0]
+	for key, want := range map[string]string{"GET_DATA_1": "1", "GET_DATA_2": "2", "SINGLE": ""} {
+		f, ok := pc.Functions[key]
+		if !ok {
+			t.Errorf("no %s in %v", key, slices.Collect(maps.Keys(pc.Functions)))
+			continue
+		}
+		if f.Overload != want || f.Arguments[0].Overload != want {
+			t.Errorf("%s: got overload %q, wanted %q", key, f.Overload, want)
+		}
+	}
+
+	functions := oracall.ApplyAnnotations(oracall.ParsePackageCache(ctx, pc, nil), pc.Annotations)
+	names := make([]string, 0, len(functions))
+	for _, f := range functions {
+		names = append(names, f.RPCName())
+		if f.RPCName() != "GetDataById" {
+			continue
+		}
+		qry, binds, _, err := f.PlsqlCall()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(qry, "Pkg.get_data(p_id=>") || len(binds) != 2 {
+			t.Errorf("got %q %v", qry, binds)
+		}
+	}
+	slices.Sort(names)
+	if d := cmp.Diff([]string{"GetDataById", "GetData_1", "Single"}, names); d != "" {
+		t.Error(d)
+	}
+}